@@ -0,0 +1,265 @@
+package omada
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	AclTypeGateway = 0
+	AclTypeSwitch  = 1
+	AclTypeEap     = 2
+)
+
+const (
+	AclPolicyDeny   = 0
+	AclPolicyPermit = 1
+)
+
+const (
+	AclTargetNetwork     = 0
+	AclTargetIpGroup     = 1
+	AclTargetIpPortGroup = 2
+	AclTargetSsid        = 3
+)
+
+const (
+	AclProtocolAll  = 256
+	AclProtocolIcmp = 1
+	AclProtocolTcp  = 6
+	AclProtocolUdp  = 17
+)
+
+type Acl struct {
+	Id              string       `json:"id,omitempty"`
+	Type            int          `json:"type"`
+	Index           int          `json:"index,omitempty"`
+	Name            string       `json:"name"`
+	Status          bool         `json:"status"`
+	Policy          int          `json:"policy"`
+	Protocols       []int        `json:"protocols"`
+	SourceType      int          `json:"sourceType"`
+	SourceIds       []string     `json:"sourceIds"`
+	DestinationType int          `json:"destinationType"`
+	DestinationIds  []string     `json:"destinationIds"`
+	Direction       AclDirection `json:"direction"`
+	BiDirectional   bool         `json:"biDirectional"`
+
+	// SourceNames and DestinationNames hold network names when the matching
+	// type is AclTargetNetwork. They are filled in on read, leaving out ids
+	// of networks that no longer exist. On create and update they are only
+	// resolved to network ids when SourceIds or DestinationIds is empty, so
+	// clear the ids to change an acl by network name.
+	SourceNames      []string `json:"-"`
+	DestinationNames []string `json:"-"`
+}
+
+type AclDirection struct {
+	LanToWan bool     `json:"lanToWan"`
+	LanToLan bool     `json:"lanToLan"`
+	WanInIds []string `json:"wanInIds,omitempty"`
+	VpnInIds []string `json:"vpnInIds,omitempty"`
+}
+
+type aclReorder struct {
+	Type   int      `json:"type"`
+	AclIds []string `json:"aclIds"`
+}
+
+func (a Acl) Validate() error {
+
+	if a.Name == "" {
+		return fmt.Errorf("acl name is required")
+	}
+
+	if a.Type < AclTypeGateway || a.Type > AclTypeEap {
+		return fmt.Errorf("acl %s: unknown type: %d", a.Name, a.Type)
+	}
+
+	if a.Policy != AclPolicyDeny && a.Policy != AclPolicyPermit {
+		return fmt.Errorf("acl %s: unknown policy: %d", a.Name, a.Policy)
+	}
+
+	if len(a.Protocols) == 0 {
+		return fmt.Errorf("acl %s: at least one protocol is required", a.Name)
+	}
+
+	for _, target := range []int{a.SourceType, a.DestinationType} {
+		if target < AclTargetNetwork || target > AclTargetSsid {
+			return fmt.Errorf("acl %s: unknown source or destination type: %d", a.Name, target)
+		}
+	}
+
+	if a.Type != AclTypeEap && (a.SourceType == AclTargetSsid || a.DestinationType == AclTargetSsid) {
+		return fmt.Errorf("acl %s: ssid targets are only valid for eap acls", a.Name)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetAcls(aclType int) ([]Acl, error) {
+
+	var acls []Acl
+	if err := c.getPaged(c.siteId, fmt.Sprintf("setting/firewall/acls?type=%d", aclType), &acls); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(acls, func(i, j int) bool {
+		return acls[i].Index < acls[j].Index
+	})
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for _, network := range networks {
+		names[network.Id] = network.Name
+	}
+
+	for i, acl := range acls {
+		if acl.SourceType == AclTargetNetwork {
+			acls[i].SourceNames = lookupNames(names, acl.SourceIds)
+		}
+		if acl.DestinationType == AclTargetNetwork {
+			acls[i].DestinationNames = lookupNames(names, acl.DestinationIds)
+		}
+	}
+
+	return acls, nil
+
+}
+
+func lookupNames(names map[string]string, ids []string) []string {
+
+	var resolved []string
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			resolved = append(resolved, name)
+		}
+	}
+
+	return resolved
+
+}
+
+func (c *Controller) GetAcl(aclType int, id string) (Acl, error) {
+
+	acls, err := c.GetAcls(aclType)
+	if err != nil {
+		return Acl{}, err
+	}
+
+	for _, acl := range acls {
+		if acl.Id == id {
+			return acl, nil
+		}
+	}
+
+	return Acl{}, fmt.Errorf("acl not found: %s", id)
+
+}
+
+func (c *Controller) CreateAcl(acl Acl) (Acl, error) {
+
+	acl.Id = ""
+	acl, err := c.prepareAcl(acl)
+	if err != nil {
+		return Acl{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/firewall/acls")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, acl, &created); err != nil {
+		return Acl{}, err
+	}
+
+	acl.Id = created.Id
+	return acl, nil
+
+}
+
+func (c *Controller) UpdateAcl(acl Acl) error {
+
+	if acl.Id == "" {
+		return fmt.Errorf("acl id is required")
+	}
+
+	acl, err := c.prepareAcl(acl)
+	if err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/firewall/acls/"+acl.Id)
+	return c.doRequest("PATCH", url, acl, nil)
+
+}
+
+func (c *Controller) DeleteAcl(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/firewall/acls/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+// ReorderAcls sets the evaluation order of all acls of one type. Every
+// existing acl id of that type must be given exactly once.
+func (c *Controller) ReorderAcls(aclType int, ids []string) error {
+
+	acls, err := c.GetAcls(aclType)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) != len(acls) {
+		return fmt.Errorf("expected %d acl ids, got: %d", len(acls), len(ids))
+	}
+
+	existing := make(map[string]bool)
+	for _, acl := range acls {
+		existing[acl.Id] = true
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			return fmt.Errorf("acl not found or given twice: %s", id)
+		}
+		delete(existing, id)
+	}
+
+	url := c.siteURL(c.siteId, "setting/firewall/acls/index")
+	return c.doRequest("PATCH", url, aclReorder{Type: aclType, AclIds: ids}, nil)
+
+}
+
+func (c *Controller) prepareAcl(acl Acl) (Acl, error) {
+
+	if err := acl.Validate(); err != nil {
+		return Acl{}, err
+	}
+
+	needSource := acl.SourceType == AclTargetNetwork && len(acl.SourceIds) == 0 && len(acl.SourceNames) > 0
+	needDestination := acl.DestinationType == AclTargetNetwork && len(acl.DestinationIds) == 0 && len(acl.DestinationNames) > 0
+	if !needSource && !needDestination {
+		return acl, nil
+	}
+
+	var err error
+	if needSource {
+		acl.SourceIds, err = c.NetworkIdsByName(acl.SourceNames...)
+		if err != nil {
+			return Acl{}, fmt.Errorf("acl %s: %w", acl.Name, err)
+		}
+	}
+	if needDestination {
+		acl.DestinationIds, err = c.NetworkIdsByName(acl.DestinationNames...)
+		if err != nil {
+			return Acl{}, fmt.Errorf("acl %s: %w", acl.Name, err)
+		}
+	}
+
+	return acl, nil
+
+}
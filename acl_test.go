@@ -0,0 +1,81 @@
+package omada
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAclNetworkNames(t *testing.T) {
+
+	acls := []Acl{{
+		Id: "acl", Name: "block", Protocols: []int{AclProtocolAll},
+		SourceType: AclTargetNetwork, SourceIds: []string{"lan", "gone"},
+		DestinationType: AclTargetNetwork, DestinationIds: []string{"iot"},
+	}}
+
+	var written []Acl
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		switch {
+		case r.Method != "GET":
+			var acl Acl
+			json.NewDecoder(r.Body).Decode(&acl)
+			written = append(written, acl)
+			result = createdResponse{Id: "new"}
+		case strings.HasSuffix(r.URL.Path, "/setting/lan/networks"):
+			result = map[string]interface{}{"data": []OmadaNetwork{
+				{Id: "lan", Name: "LAN"}, {Id: "iot", Name: "IoT"}, {Id: "guest", Name: "Guest"},
+			}}
+		case strings.HasSuffix(r.URL.Path, "/setting/firewall/acls"):
+			result = map[string]interface{}{"data": acls}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "site"}
+
+	acl, err := c.GetAcl(AclTypeGateway, "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(acl.SourceNames, ",") != "LAN" || strings.Join(acl.DestinationNames, ",") != "IoT" {
+		t.Fatalf("got names %v and %v", acl.SourceNames, acl.DestinationNames)
+	}
+
+	// ids edited after a read win over the names filled in on read, and an
+	// acl still pointing at a deleted network can be updated
+	acl.DestinationIds = []string{"guest"}
+	if err := c.UpdateAcl(acl); err != nil {
+		t.Fatal(err)
+	}
+
+	// names are resolved when the ids are cleared
+	acl.SourceIds = nil
+	acl.SourceNames = []string{"Guest", "IoT"}
+	if _, err := c.CreateAcl(acl); err != nil {
+		t.Fatal(err)
+	}
+
+	acl.SourceNames = []string{"missing"}
+	if _, err := c.CreateAcl(acl); err == nil {
+		t.Error("expected an error for an unknown network name")
+	}
+
+	if len(written) != 2 {
+		t.Fatalf("got %d writes, want 2", len(written))
+	}
+	if got := strings.Join(written[0].SourceIds, ","); got != "lan,gone" {
+		t.Errorf("update: got source ids %s", got)
+	}
+	if got := strings.Join(written[0].DestinationIds, ","); got != "guest" {
+		t.Errorf("update: got destination ids %s", got)
+	}
+	if got := strings.Join(written[1].SourceIds, ","); got != "guest,iot" {
+		t.Errorf("create: got source ids %s", got)
+	}
+
+}
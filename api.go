@@ -0,0 +1,152 @@
+package omada
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type apiResponse struct {
+	ErrorCode int             `json:"errorCode"`
+	Msg       string          `json:"msg"`
+	Result    json.RawMessage `json:"result"`
+}
+
+type createdResponse struct {
+	Id string `json:"id"`
+}
+
+// errorCodeLoginRequired is returned once the session has expired or was
+// logged out elsewhere.
+const errorCodeLoginRequired = -1200
+
+// ApiError is returned when the controller answers with a nonzero errorCode.
+type ApiError struct {
+	Code int
+	Msg  string
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("omada api error, code: %d, message: %s", e.Code, e.Msg)
+}
+
+// StatusError is returned when the controller answers with an http status
+// other than 200.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.StatusCode)
+}
+
+// IsAuthError reports whether err means the session is no longer valid and
+// logging in again may help, as opposed to a timeout or a server error.
+func IsAuthError(err error) bool {
+
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == errorCodeLoginRequired
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden
+	}
+
+	return false
+
+}
+
+func (c *Controller) siteURL(siteId string, path string) string {
+	return fmt.Sprintf("%s/%s/api/v2/sites/%s/%s", c.baseURL, c.controllerId, siteId, path)
+}
+
+// doRequest sends an authenticated request to the controller, checks both the
+// http status and the omada error code, and decodes the result into result.
+func (c *Controller) doRequest(method string, url string, body interface{}, result interface{}) error {
+
+	var reqBody io.Reader
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(bodyJSON)
+	}
+
+	req, err := http.NewRequestWithContext(c.context(), method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Add("Csrf-Token", c.token)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+
+	var apiResponse apiResponse
+	if err := json.NewDecoder(res.Body).Decode(&apiResponse); err != nil {
+		return err
+	}
+
+	if apiResponse.ErrorCode != 0 {
+		return &ApiError{Code: apiResponse.ErrorCode, Msg: apiResponse.Msg}
+	}
+
+	if result == nil || len(apiResponse.Result) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(apiResponse.Result, result)
+
+}
+
+type pagedResult struct {
+	TotalRows   int             `json:"totalRows"`
+	CurrentPage int             `json:"currentPage"`
+	CurrentSize int             `json:"currentSize"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// getPaged fetches a list endpoint that wraps its results in the usual
+// totalRows/currentPage/data envelope.
+func (c *Controller) getPaged(siteId string, path string, result interface{}) error {
+	_, err := c.getPage(siteId, path, 1, 999, result)
+	return err
+}
+
+func (c *Controller) getPage(siteId string, path string, page int, pageSize int, result interface{}) (pagedResult, error) {
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	url := c.siteURL(siteId, fmt.Sprintf("%s%scurrentPage=%d&currentPageSize=%d", path, sep, page, pageSize))
+
+	var paged pagedResult
+	if err := c.doRequest("GET", url, nil, &paged); err != nil {
+		return pagedResult{}, err
+	}
+
+	if len(paged.Data) == 0 {
+		return paged, nil
+	}
+
+	return paged, json.Unmarshal(paged.Data, result)
+
+}
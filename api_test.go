@@ -0,0 +1,69 @@
+package omada
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsAuthError(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"login required", &ApiError{Code: errorCodeLoginRequired}, true},
+		{"wrapped login required", fmt.Errorf("site a: %w", &ApiError{Code: errorCodeLoginRequired}), true},
+		{"other api error", &ApiError{Code: -1001}, false},
+		{"unauthorized", &StatusError{StatusCode: http.StatusUnauthorized}, true},
+		{"forbidden", &StatusError{StatusCode: http.StatusForbidden}, true},
+		{"server error", &StatusError{StatusCode: http.StatusBadGateway}, false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"nil", nil, false},
+	}
+
+	for _, test := range tests {
+		if got := IsAuthError(test.err); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+}
+
+func TestDoRequestErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		auth   bool
+	}{
+		{"expired session", http.StatusOK, `{"errorCode":-1200,"msg":"Login required"}`, true},
+		{"unauthorized", http.StatusUnauthorized, ``, true},
+		{"bad gateway", http.StatusBadGateway, ``, false},
+		{"invalid request", http.StatusOK, `{"errorCode":-1001,"msg":"Invalid request parameters"}`, false},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		}))
+
+		c := Controller{httpClient: server.Client(), baseURL: server.URL}
+		err := c.doRequest("GET", server.URL, nil, nil)
+		server.Close()
+
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		if got := IsAuthError(err); got != test.auth {
+			t.Errorf("%s: IsAuthError(%v) = %v, want %v", test.name, err, got, test.auth)
+		}
+	}
+
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	"github.com/dougbw/go-omada/exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func main() {
+
+	// variables
+	controllerUrl := flag.String("url", "https://10.0.0.10", "omada controller url")
+	siteName := flag.String("site", "Default", "site used to log in")
+	siteIds := flag.String("site-ids", "", "comma separated site ids to export, defaults to all sites")
+	listen := flag.String("listen", ":9202", "address to serve metrics on")
+	cacheTTL := flag.Duration("cache-ttl", 60*time.Second, "how long controller results are reused between scrapes")
+	flag.Parse()
+
+	user, present := os.LookupEnv("OMADA_USERNAME")
+	if !present {
+		log.Fatal("⛔ required environment variable not set: OMADA_USERNAME")
+	}
+	pass, present := os.LookupEnv("OMADA_PASSWORD")
+	if !present {
+		log.Fatal("⛔ required environment variable not set: OMADA_PASSWORD")
+	}
+
+	// setup
+	controller := omada.New(*controllerUrl)
+	err := controller.GetControllerInfo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// login
+	err = controller.Login(user, pass, *siteName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// serve
+	opts := exporter.Options{
+		CacheTTL: *cacheTTL,
+		Relogin: func() error {
+			return controller.Login(user, pass, *siteName)
+		},
+		OnError: func(err error) {
+			log.Printf("scrape: %s", err)
+		},
+	}
+	if *siteIds != "" {
+		opts.SiteIds = strings.Split(*siteIds, ",")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exporter.NewCollector(&controller, opts))
+
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	log.Printf("serving metrics on %s/metrics", *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+
+}
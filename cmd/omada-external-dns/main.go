@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	omada "github.com/dougbw/go-omada"
+	"github.com/dougbw/go-omada/externaldns"
+)
+
+func main() {
+
+	// variables
+	controllerUrl := flag.String("url", "https://10.0.0.10", "omada controller url")
+	siteName := flag.String("site", "Default", "site used to log in")
+	listen := flag.String("listen", "localhost:8888", "address to serve the webhook on")
+	domain := flag.String("domain", "", "domain for hosts whose network has no domain set")
+	domains := flag.String("domain-filter", "", "comma separated domains to serve, defaults to the network domains")
+	ttl := flag.Int64("ttl", 300, "ttl of the endpoints returned")
+	staticDns := flag.Bool("static-dns", false, "write records to the gateway static dns entries, run external-dns with --registry=noop")
+	flag.Parse()
+
+	user, present := os.LookupEnv("OMADA_USERNAME")
+	if !present {
+		log.Fatal("⛔ required environment variable not set: OMADA_USERNAME")
+	}
+	pass, present := os.LookupEnv("OMADA_PASSWORD")
+	if !present {
+		log.Fatal("⛔ required environment variable not set: OMADA_PASSWORD")
+	}
+
+	// setup
+	controller := omada.New(*controllerUrl)
+	err := controller.GetControllerInfo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// login
+	err = controller.Login(user, pass, *siteName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// serve
+	opts := externaldns.Options{
+		Domain: *domain,
+		Ttl:    *ttl,
+		Relogin: func() error {
+			return controller.Login(user, pass, *siteName)
+		},
+	}
+	if *domains != "" {
+		opts.Domains = strings.Split(*domains, ",")
+	}
+	if *staticDns {
+		opts.Writer = externaldns.NewStaticDnsWriter(&controller)
+	} else {
+		opts.OnReadOnly = func(changes externaldns.Changes) {
+			log.Printf("read only, ignoring %d creates, %d updates and %d deletes", len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+		}
+	}
+
+	provider := externaldns.New(&controller, opts)
+
+	log.Printf("serving external-dns webhook on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, provider.Handler()))
+
+}
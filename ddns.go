@@ -0,0 +1,142 @@
+package omada
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const (
+	DdnsProviderTpLink = "tplink"
+	DdnsProviderDynDns = "dyndns"
+	DdnsProviderNoIp   = "noip"
+	DdnsProviderCustom = "custom"
+)
+
+type Ddns struct {
+	Id             string `json:"id,omitempty"`
+	Status         bool   `json:"status"`
+	Provider       string `json:"serviceProvider"`
+	WanPortId      string `json:"interfaceId"`
+	Hostname       string `json:"domainName"`
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	UpdateUrl      string `json:"updateUrl,omitempty"`
+	UpdateInterval int    `json:"updateInterval,omitempty"`
+}
+
+type DdnsStatus struct {
+	Id             string `json:"id"`
+	Hostname       string `json:"domainName"`
+	State          string `json:"status"`
+	UpdatedIp      string `json:"updatedIp"`
+	LastUpdateTime int64  `json:"lastUpdateTime"`
+}
+
+func (s DdnsStatus) LastUpdated() time.Time {
+	if s.LastUpdateTime == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(s.LastUpdateTime)
+}
+
+func (d Ddns) Validate() error {
+
+	if d.Hostname == "" {
+		return fmt.Errorf("ddns hostname is required")
+	}
+
+	if d.WanPortId == "" {
+		return fmt.Errorf("ddns %s: wan interface is required", d.Hostname)
+	}
+
+	switch d.Provider {
+	case DdnsProviderTpLink:
+	case DdnsProviderDynDns, DdnsProviderNoIp:
+		if d.Username == "" || d.Password == "" {
+			return fmt.Errorf("ddns %s: username and password are required for %s", d.Hostname, d.Provider)
+		}
+	case DdnsProviderCustom:
+		u, err := url.Parse(d.UpdateUrl)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("ddns %s: invalid update url: %s", d.Hostname, d.UpdateUrl)
+		}
+	default:
+		return fmt.Errorf("ddns %s: unknown provider: %s", d.Hostname, d.Provider)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetDdns() ([]Ddns, error) {
+
+	var entries []Ddns
+	if err := c.getPaged(c.siteId, "setting/service/ddns", &entries); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Hostname < entries[j].Hostname
+	})
+
+	return entries, nil
+
+}
+
+func (c *Controller) CreateDdns(entry Ddns) (Ddns, error) {
+
+	entry.Id = ""
+	if err := entry.Validate(); err != nil {
+		return Ddns{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/service/ddns")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, entry, &created); err != nil {
+		return Ddns{}, err
+	}
+
+	entry.Id = created.Id
+	return entry, nil
+
+}
+
+func (c *Controller) UpdateDdns(entry Ddns) error {
+
+	if entry.Id == "" {
+		return fmt.Errorf("ddns id is required")
+	}
+
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/service/ddns/"+entry.Id)
+	return c.doRequest("PATCH", url, entry, nil)
+
+}
+
+func (c *Controller) DeleteDdns(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/service/ddns/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) GetDdnsStatus() ([]DdnsStatus, error) {
+
+	var statuses []DdnsStatus
+	if err := c.getPaged(c.siteId, "setting/service/ddns/status", &statuses); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Hostname < statuses[j].Hostname
+	})
+
+	return statuses, nil
+
+}
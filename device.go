@@ -1,18 +1,9 @@
 package omada
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"sort"
 )
 
-type deviceResponse struct {
-	ErrorCode int      `json:"errorCode"`
-	Msg       string   `json:"msg"`
-	Result    []Device `json:"result"`
-}
-
 type Device struct {
 	Type            string `json:"type"`
 	Mac             string `json:"mac"`
@@ -136,84 +127,74 @@ type Device struct {
 	FanStatus        int     `json:"fanStatus,omitempty"`
 	PoeSupport       bool    `json:"poeSupport,omitempty"`
 	DnsName          string
+	WlanGroupName    string
+	SiteId           string
 }
 
 func (c *Controller) GetDevices() ([]Device, error) {
 
-	url := fmt.Sprintf("%s/%s/api/v2/sites/%s/devices?currentPage=1&currentPageSize=999", c.baseURL, c.controllerId, c.siteId)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Add("Csrf-Token", c.token)
+	_, devices, err := c.getSiteHosts(c.siteId)
+	return devices, err
 
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
+}
 
-	if res.StatusCode != http.StatusOK {
-		err = fmt.Errorf("status code: %d", res.StatusCode)
-		return nil, err
-	}
+func (c *Controller) GetAllDevices() ([]Device, error) {
 
-	var deviceResponse deviceResponse
-	if err := json.NewDecoder(res.Body).Decode(&deviceResponse); err != nil {
-		return nil, err
-	}
+	_, devices, err := c.GetAllHosts()
+	return devices, err
+
+}
+
+func (c *Controller) getSiteDevices(siteId string) ([]Device, error) {
+
+	url := c.siteURL(siteId, "devices?currentPage=1&currentPageSize=999")
 
 	var devices []Device
-	for _, device := range deviceResponse.Result {
-		device.DnsName = makeDNSSafe(device.Name)
-		devices = append(devices, device)
+	if err := c.doRequest("GET", url, nil, &devices); err != nil {
+		return nil, err
 	}
 
-	sort.Slice(devices, func(i, j int) bool {
-		return devices[i].Name < devices[j].Name
-	})
+	for i := range devices {
+		devices[i].SiteId = siteId
+	}
 
 	return devices, nil
 
 }
 
-func (c *Controller) GetAllDevices() ([]Device, error) {
+func (c *Controller) GetDevice(mac string) (Device, error) {
 
-	var allDevices []Device
-	for _, v := range c.allSiteIds {
-		url := fmt.Sprintf("%s/%s/api/v2/sites/%s/devices?currentPage=1&currentPageSize=999", c.baseURL, c.controllerId, v)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Accept", "application/json")
-		req.Header.Add("Csrf-Token", c.token)
+	devices, err := c.GetDevices()
+	if err != nil {
+		return Device{}, err
+	}
 
-		res, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, err
+	for _, device := range devices {
+		if device.Mac == mac {
+			return device, nil
 		}
+	}
 
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("status code: %d", res.StatusCode)
-			return nil, err
-		}
+	return Device{}, fmt.Errorf("device not found: %s", mac)
 
-		var deviceResponse deviceResponse
-		if err := json.NewDecoder(res.Body).Decode(&deviceResponse); err != nil {
-			return nil, err
-		}
+}
 
-		for _, device := range deviceResponse.Result {
-			device.DnsName = makeDNSSafe(device.Name)
-			allDevices = append(allDevices, device)
-		}
+// findDevice looks a device up without naming it, so checks that only need
+// its type or capabilities do not also fetch every client. DnsName is left
+// empty.
+func (c *Controller) findDevice(mac string) (Device, error) {
 
-		sort.Slice(allDevices, func(i, j int) bool {
-			return allDevices[i].Name < allDevices[j].Name
-		})
+	devices, err := c.getSiteDevices(c.siteId)
+	if err != nil {
+		return Device{}, err
+	}
+
+	for _, device := range devices {
+		if normalizeMac(device.Mac) == normalizeMac(mac) {
+			return device, nil
+		}
 	}
 
-	return allDevices, nil
+	return Device{}, fmt.Errorf("device not found: %s", mac)
 
 }
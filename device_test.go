@@ -0,0 +1,46 @@
+package omada
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeviceChecksSkipClients(t *testing.T) {
+
+	devices := []Device{
+		{Mac: "AA-BB-CC-00-00-01", Type: "gateway"},
+		{Mac: "AA-BB-CC-00-00-02", Type: "switch"},
+	}
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": devices})
+	}))
+	defer server.Close()
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "site"}
+
+	if err := c.checkGateway("aa:bb:cc:00:00:01"); err != nil {
+		t.Error(err)
+	}
+	if err := c.checkGateway("AA-BB-CC-00-00-02"); err == nil {
+		t.Error("expected an error for a switch")
+	}
+	if err := c.checkLagSupport("AA-BB-CC-00-00-02"); err == nil {
+		t.Error("expected an error for a switch without lag support")
+	}
+	if err := c.checkGateway("AA-BB-CC-00-00-09"); err == nil {
+		t.Error("expected an error for an unknown device")
+	}
+
+	for _, path := range paths {
+		if !strings.HasSuffix(path, "/devices") {
+			t.Errorf("unexpected request %s", path)
+		}
+	}
+
+}
@@ -0,0 +1,217 @@
+// Package dns renders the clients and devices known to an Omada controller
+// as rfc 1035 zone files, /etc/hosts blocks, dnsmasq host-record lines and
+// unbound local-data, using each network's domain as the name suffix.
+package dns
+
+import (
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+type Options struct {
+	// Ttl applies to every record written. Defaults to 300 seconds.
+	Ttl uint32
+
+	// Domain is used for hosts whose network has no domain set. Hosts with
+	// neither are left out of zones but still written as short names.
+	Domain string
+
+	// Serial is written to every zone. When zero NextSerial is used with
+	// PreviousSerial, so passing the serial of the last run is enough to
+	// keep it increasing.
+	Serial         uint32
+	PreviousSerial uint32
+
+	// Nameserver and Hostmaster fill the SOA and NS records and default to
+	// ns.<domain> and hostmaster.<domain> of the zone's network. The
+	// nameserver name is reserved, a host that would get the same name is
+	// left out so the name never resolves to two addresses.
+	Nameserver string
+	Hostmaster string
+
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+}
+
+type HostRecord struct {
+	Kind   string
+	Name   string
+	Domain string
+	Mac    string
+	Addr   netip.Addr
+}
+
+func (r HostRecord) Fqdn() string {
+	if r.Domain == "" {
+		return r.Name
+	}
+	return r.Name + "." + r.Domain
+}
+
+type Generator struct {
+	opts     Options
+	records  []HostRecord
+	networks []omada.OmadaNetwork
+}
+
+func New(hosts []omada.HostNetwork, networks []omada.OmadaNetwork, opts Options) *Generator {
+
+	if opts.Ttl == 0 {
+		opts.Ttl = 300
+	}
+	if opts.Serial == 0 {
+		opts.Serial = NextSerial(opts.PreviousSerial, time.Now())
+	}
+	if opts.Refresh == 0 {
+		opts.Refresh = 3600
+	}
+	if opts.Retry == 0 {
+		opts.Retry = 600
+	}
+	if opts.Expire == 0 {
+		opts.Expire = 604800
+	}
+	opts.Domain = trimDot(opts.Domain)
+
+	seen := make(map[string]bool)
+	var records []HostRecord
+	for _, host := range hosts {
+		if host.DnsName == "" || !host.Ip.IsValid() {
+			continue
+		}
+
+		record := HostRecord{
+			Kind:   host.Kind,
+			Name:   host.DnsName,
+			Domain: trimDot(host.Domain),
+			Mac:    host.Mac,
+			Addr:   host.Ip.Unmap(),
+		}
+		if record.Domain == "" {
+			record.Domain = opts.Domain
+		}
+		if record.Domain != "" && record.Fqdn() == nameserverFor(opts, record.Domain) {
+			continue
+		}
+
+		key := record.Fqdn() + "/" + record.Addr.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Fqdn() != records[j].Fqdn() {
+			return records[i].Fqdn() < records[j].Fqdn()
+		}
+		return records[i].Addr.Less(records[j].Addr)
+	})
+
+	return &Generator{
+		opts:     opts,
+		records:  records,
+		networks: networks,
+	}
+}
+
+func FromController(c *omada.Controller, opts Options) (*Generator, error) {
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	clients, devices, err := c.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := omada.NewNetworkResolver(networks)
+	hosts := resolver.ResolveClients(clients)
+	hosts = append(hosts, resolver.ResolveDevices(devices)...)
+
+	return New(hosts, networks, opts), nil
+
+}
+
+// FromAllSites builds one generator for every site the controller user can
+// access. Each site is resolved against its own networks, so sites reusing
+// the same subnets still get the right domains.
+func FromAllSites(c *omada.Controller, opts Options) (*Generator, error) {
+
+	var hosts []omada.HostNetwork
+	var networks []omada.OmadaNetwork
+	for _, siteId := range c.SiteIds() {
+		site, err := c.WithSite(siteId)
+		if err != nil {
+			return nil, err
+		}
+
+		generator, err := FromController(site, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range generator.records {
+			hosts = append(hosts, omada.HostNetwork{
+				Kind:    record.Kind,
+				DnsName: record.Name,
+				Mac:     record.Mac,
+				Ip:      record.Addr,
+				Domain:  record.Domain,
+			})
+		}
+		networks = append(networks, generator.networks...)
+	}
+
+	return New(hosts, networks, opts), nil
+
+}
+
+func (g *Generator) Records() []HostRecord {
+	return append([]HostRecord{}, g.records...)
+}
+
+func (g *Generator) Networks() []omada.OmadaNetwork {
+	return append([]omada.OmadaNetwork{}, g.networks...)
+}
+
+func (g *Generator) Serial() uint32 {
+	return g.opts.Serial
+}
+
+// NextSerial returns a YYYYMMDDnn serial for now that is always greater than
+// previous. When previous already uses today's date, or is ahead of it, it is
+// simply incremented.
+func NextSerial(previous uint32, now time.Time) uint32 {
+
+	date, _ := strconv.ParseUint(now.UTC().Format("20060102"), 10, 32)
+	serial := uint32(date) * 100
+	if serial > previous {
+		return serial
+	}
+
+	return previous + 1
+
+}
+
+func nameserverFor(opts Options, domain string) string {
+
+	if nameserver := trimDot(opts.Nameserver); nameserver != "" {
+		return nameserver
+	}
+
+	return "ns." + domain
+
+}
+
+func trimDot(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,117 @@
+package dns
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	hostsBegin = "# BEGIN go-omada"
+	hostsEnd   = "# END go-omada"
+)
+
+// Hosts returns an /etc/hosts block wrapped in begin and end markers so it
+// can be swapped into an existing file with ReplaceHostsBlock.
+func (g *Generator) Hosts() string {
+
+	var sb strings.Builder
+
+	sb.WriteString(hostsBegin + "\n")
+	for _, record := range g.records {
+		if record.Domain == "" {
+			fmt.Fprintf(&sb, "%s\t%s\n", record.Addr, record.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\t%s %s\n", record.Addr, record.Fqdn(), record.Name)
+	}
+	sb.WriteString(hostsEnd + "\n")
+
+	return sb.String()
+
+}
+
+// ReplaceHostsBlock swaps the go-omada block in an existing hosts file for
+// block, or appends block when the file does not have one yet.
+func ReplaceHostsBlock(existing string, block string) string {
+
+	start := strings.Index(existing, hostsBegin)
+	end := strings.Index(existing, hostsEnd)
+	if start == -1 || end == -1 || end < start {
+		if existing != "" && !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+		return existing + block
+	}
+
+	end += len(hostsEnd)
+	if end < len(existing) && existing[end] == '\n' {
+		end++
+	}
+
+	return existing[:start] + block + existing[end:]
+
+}
+
+// Dnsmasq returns host-record lines, one per address. dnsmasq answers PTR
+// queries for host-record entries itself.
+func (g *Generator) Dnsmasq() string {
+
+	var sb strings.Builder
+	for _, record := range g.records {
+		fmt.Fprintf(&sb, "host-record=%s,%s,%d\n", record.Fqdn(), record.Addr, g.opts.Ttl)
+	}
+
+	return sb.String()
+
+}
+
+// Unbound returns server clause local-zone, local-data and local-data-ptr
+// lines. The zones are transparent so names not generated here still
+// resolve upstream. Hosts without a domain are skipped.
+func (g *Generator) Unbound() string {
+
+	domains := make(map[string]bool)
+	for _, record := range g.records {
+		if record.Domain != "" {
+			domains[record.Domain] = true
+		}
+	}
+
+	var sorted []string
+	for domain := range domains {
+		sorted = append(sorted, domain)
+	}
+	sort.Strings(sorted)
+
+	var sb strings.Builder
+	for _, domain := range sorted {
+		fmt.Fprintf(&sb, "local-zone: \"%s.\" transparent\n", domain)
+	}
+	for _, record := range g.records {
+		if record.Domain == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "local-data: \"%s. %d IN %s %s\"\n", record.Fqdn(), g.opts.Ttl, addressType(record.Addr), record.Addr)
+		fmt.Fprintf(&sb, "local-data-ptr: \"%s %d %s.\"\n", record.Addr, g.opts.Ttl, record.Fqdn())
+	}
+
+	return sb.String()
+
+}
+
+func (g *Generator) WriteHosts(w io.Writer) error {
+	_, err := io.WriteString(w, g.Hosts())
+	return err
+}
+
+func (g *Generator) WriteDnsmasq(w io.Writer) error {
+	_, err := io.WriteString(w, g.Dnsmasq())
+	return err
+}
+
+func (g *Generator) WriteUnbound(w io.Writer) error {
+	_, err := io.WriteString(w, g.Unbound())
+	return err
+}
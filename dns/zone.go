@@ -0,0 +1,298 @@
+package dns
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+type ZoneRecord struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+type Zone struct {
+	Origin     string
+	Nameserver string
+	Hostmaster string
+	Serial     uint32
+	Ttl        uint32
+	Refresh    uint32
+	Retry      uint32
+	Expire     uint32
+	Records    []ZoneRecord
+}
+
+// ForwardZones returns one zone per domain holding the A and AAAA records of
+// every host in it. When the nameserver is inside the zone and a network with
+// that domain exists, its gateway address is added as the nameserver's glue.
+func (g *Generator) ForwardZones() []Zone {
+
+	byDomain := make(map[string][]HostRecord)
+	for _, record := range g.records {
+		if record.Domain == "" {
+			continue
+		}
+		byDomain[record.Domain] = append(byDomain[record.Domain], record)
+	}
+
+	var zones []Zone
+	for domain, records := range byDomain {
+		zone := g.newZone(domain, domain)
+
+		if strings.HasSuffix(zone.Nameserver, "."+domain) {
+			if addr, ok := g.gatewayFor(domain); ok {
+				zone.Records = append(zone.Records, ZoneRecord{
+					Name:  strings.TrimSuffix(zone.Nameserver, "."+domain),
+					Type:  addressType(addr),
+					Value: addr.String(),
+				})
+			}
+		}
+
+		for _, record := range records {
+			zone.Records = append(zone.Records, ZoneRecord{
+				Name:  record.Name,
+				Type:  addressType(record.Addr),
+				Value: record.Addr.String(),
+			})
+		}
+
+		zones = append(zones, zone)
+	}
+
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Origin < zones[j].Origin
+	})
+
+	return zones
+
+}
+
+// ReverseZones returns the in-addr.arpa and ip6.arpa zones covering each
+// network's subnet and ipv6 prefix, with a PTR record for every host inside.
+func (g *Generator) ReverseZones() []Zone {
+
+	zones := make(map[string]*Zone)
+	prefixes := make(map[string]netip.Prefix)
+
+	for _, network := range g.networks {
+		domain := trimDot(network.Domain)
+		if domain == "" {
+			domain = g.opts.Domain
+		}
+
+		var subnets []netip.Prefix
+		if prefix, err := network.Prefix(); err == nil {
+			subnets = append(subnets, prefix)
+		}
+		if network.Ipv6 != nil && network.Ipv6.Prefix != "" {
+			if prefix, err := netip.ParsePrefix(network.Ipv6.Prefix); err == nil {
+				subnets = append(subnets, prefix.Masked())
+			}
+		}
+
+		for _, subnet := range subnets {
+			for _, prefix := range reverseZonePrefixes(subnet) {
+				origin := reverseOrigin(prefix)
+				if _, ok := zones[origin]; ok {
+					continue
+				}
+				zone := g.newZone(origin, domain)
+				zones[origin] = &zone
+				prefixes[origin] = prefix
+			}
+		}
+	}
+
+	for origin, zone := range zones {
+		for _, record := range g.records {
+			if record.Domain == "" || !prefixes[origin].Contains(record.Addr) {
+				continue
+			}
+			zone.Records = append(zone.Records, ZoneRecord{
+				Name:  relativeName(reverseName(record.Addr), origin),
+				Type:  "PTR",
+				Value: record.Fqdn() + ".",
+			})
+		}
+	}
+
+	var sorted []Zone
+	for _, zone := range zones {
+		sorted = append(sorted, *zone)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Origin < sorted[j].Origin
+	})
+
+	return sorted
+
+}
+
+func (g *Generator) newZone(origin string, domain string) Zone {
+
+	nameserver := nameserverFor(g.opts, domain)
+	hostmaster := trimDot(g.opts.Hostmaster)
+	if hostmaster == "" {
+		hostmaster = "hostmaster." + domain
+	}
+	if domain == "" {
+		nameserver, hostmaster = "localhost", "hostmaster.localhost"
+	}
+
+	return Zone{
+		Origin:     origin,
+		Nameserver: nameserver,
+		Hostmaster: hostmaster,
+		Serial:     g.opts.Serial,
+		Ttl:        g.opts.Ttl,
+		Refresh:    g.opts.Refresh,
+		Retry:      g.opts.Retry,
+		Expire:     g.opts.Expire,
+	}
+
+}
+
+func (g *Generator) gatewayFor(domain string) (netip.Addr, bool) {
+
+	for _, network := range g.networks {
+		if trimDot(network.Domain) != domain {
+			continue
+		}
+		if addr, err := network.GatewayAddr(); err == nil {
+			return addr, true
+		}
+	}
+
+	return netip.Addr{}, false
+
+}
+
+func (z Zone) String() string {
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "$ORIGIN %s.\n", z.Origin)
+	fmt.Fprintf(&sb, "$TTL %d\n", z.Ttl)
+	fmt.Fprintf(&sb, "@\tIN\tSOA\t%s. %s. (\n", z.Nameserver, z.Hostmaster)
+	fmt.Fprintf(&sb, "\t\t%d\t; serial\n", z.Serial)
+	fmt.Fprintf(&sb, "\t\t%d\t; refresh\n", z.Refresh)
+	fmt.Fprintf(&sb, "\t\t%d\t; retry\n", z.Retry)
+	fmt.Fprintf(&sb, "\t\t%d\t; expire\n", z.Expire)
+	fmt.Fprintf(&sb, "\t\t%d\t; minimum\n", z.Ttl)
+	sb.WriteString("\t)\n")
+	fmt.Fprintf(&sb, "@\tIN\tNS\t%s.\n", z.Nameserver)
+
+	for _, record := range z.Records {
+		fmt.Fprintf(&sb, "%s\tIN\t%s\t%s\n", record.Name, record.Type, record.Value)
+	}
+
+	return sb.String()
+
+}
+
+func (z Zone) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, z.String())
+	return int64(n), err
+}
+
+func addressType(addr netip.Addr) string {
+	if addr.Is4() {
+		return "A"
+	}
+	return "AAAA"
+}
+
+// reverseZonePrefixes returns the octet (ipv4) or nibble (ipv6) aligned
+// prefixes a reverse zone can be delegated on for subnet. Subnets smaller
+// than a /24 or /64 get the zone containing them and larger unaligned
+// subnets are split, so a /22 becomes four /24 zones.
+func reverseZonePrefixes(subnet netip.Prefix) []netip.Prefix {
+
+	step, max := 8, 24
+	if subnet.Addr().Is6() {
+		step, max = 4, 64
+	}
+
+	bits := subnet.Bits()
+	zoneBits := (bits + step - 1) / step * step
+	if zoneBits > max {
+		zoneBits = max
+	}
+	if zoneBits == 0 {
+		zoneBits = step
+	}
+
+	if bits >= zoneBits {
+		prefix, _ := subnet.Addr().Prefix(zoneBits)
+		return []netip.Prefix{prefix}
+	}
+
+	var prefixes []netip.Prefix
+	addr := subnet.Masked().Addr()
+	for i := 0; i < 1<<(zoneBits-bits); i++ {
+		prefix := netip.PrefixFrom(addr, zoneBits)
+		prefixes = append(prefixes, prefix)
+		addr = lastAddr(prefix).Next()
+	}
+
+	return prefixes
+
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+
+	bytes := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 0x80 >> (i % 8)
+	}
+
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+
+}
+
+func reverseOrigin(prefix netip.Prefix) string {
+
+	bytes := prefix.Addr().AsSlice()
+
+	var labels []string
+	if prefix.Addr().Is4() {
+		for i := prefix.Bits()/8 - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprint(bytes[i]))
+		}
+		return strings.Join(append(labels, "in-addr.arpa"), ".")
+	}
+
+	for i := prefix.Bits()/4 - 1; i >= 0; i-- {
+		labels = append(labels, nibble(bytes, i))
+	}
+	return strings.Join(append(labels, "ip6.arpa"), ".")
+
+}
+
+func reverseName(addr netip.Addr) string {
+	return reverseOrigin(netip.PrefixFrom(addr, addr.BitLen()))
+}
+
+func nibble(bytes []byte, i int) string {
+	b := bytes[i/2]
+	if i%2 == 0 {
+		b >>= 4
+	}
+	return fmt.Sprintf("%x", b&0x0f)
+}
+
+func relativeName(name string, origin string) string {
+	if name == origin {
+		return "@"
+	}
+	if strings.HasSuffix(name, "."+origin) {
+		return strings.TrimSuffix(name, "."+origin)
+	}
+	return name + "."
+}
@@ -0,0 +1,183 @@
+package dns
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+func TestReverseZonePrefixes(t *testing.T) {
+
+	tests := []struct {
+		subnet string
+		want   []string
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}},
+		{"10.0.0.128/25", []string{"10.0.0.0/24"}},
+		{"10.0.0.4/30", []string{"10.0.0.0/24"}},
+		{"10.0.0.1/32", []string{"10.0.0.0/24"}},
+		{"10.1.0.0/16", []string{"10.1.0.0/16"}},
+		{"10.0.4.0/22", []string{"10.0.4.0/24", "10.0.5.0/24", "10.0.6.0/24", "10.0.7.0/24"}},
+		{"10.0.0.0/15", []string{"10.0.0.0/16", "10.1.0.0/16"}},
+		{"10.0.0.0/7", []string{"10.0.0.0/8", "11.0.0.0/8"}},
+		{"2001:db8:0:1::/64", []string{"2001:db8:0:1::/64"}},
+		{"2001:db8::/48", []string{"2001:db8::/48"}},
+		{"2001:db8::/63", []string{"2001:db8::/64", "2001:db8:0:1::/64"}},
+		{"2001:db8::/46", []string{"2001:db8::/48", "2001:db8:1::/48", "2001:db8:2::/48", "2001:db8:3::/48"}},
+		{"2001:db8::1/128", []string{"2001:db8::/64"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, prefix := range reverseZonePrefixes(netip.MustParsePrefix(test.subnet).Masked()) {
+			got = append(got, prefix.String())
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s: got %v, want %v", test.subnet, got, test.want)
+		}
+	}
+
+}
+
+func TestReverseOrigin(t *testing.T) {
+
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"10.0.5.0/24", "5.0.10.in-addr.arpa"},
+		{"10.1.0.0/16", "1.10.in-addr.arpa"},
+		{"2001:db8:0:1::/64", "1.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+		{"2001:db8::/48", "0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+	}
+
+	for _, test := range tests {
+		if got := reverseOrigin(netip.MustParsePrefix(test.prefix)); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.prefix, got, test.want)
+		}
+	}
+
+}
+
+func TestNextSerial(t *testing.T) {
+
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		previous uint32
+		want     uint32
+	}{
+		{"first run", 0, 2024030100},
+		{"older date", 2024022907, 2024030100},
+		{"same day", 2024030100, 2024030101},
+		{"same day again", 2024030141, 2024030142},
+		{"ahead of today", 2024031505, 2024031506},
+		{"plain counter ahead", 3000000000, 3000000001},
+	}
+
+	for _, test := range tests {
+		if got := NextSerial(test.previous, now); got != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got, test.want)
+		}
+	}
+
+	// the date is taken in utc
+	local := time.Date(2024, 3, 2, 1, 0, 0, 0, time.FixedZone("ahead", 3*3600))
+	if got := NextSerial(0, local); got != 2024030100 {
+		t.Errorf("utc date: got %d, want 2024030100", got)
+	}
+
+}
+
+func TestForwardZones(t *testing.T) {
+
+	networks := []omada.OmadaNetwork{
+		{Name: "lan", Domain: "home.lan.", Subnet: "10.0.0.1/24"},
+	}
+	hosts := []omada.HostNetwork{
+		{DnsName: "nas", Ip: netip.MustParseAddr("10.0.0.5"), Domain: "home.lan"},
+		{DnsName: "nas", Ip: netip.MustParseAddr("10.0.0.5"), Domain: "home.lan"},
+		{DnsName: "ns", Ip: netip.MustParseAddr("10.0.0.9"), Domain: "home.lan"},
+		{DnsName: "printer", Ip: netip.MustParseAddr("::ffff:10.0.0.6")},
+		{DnsName: "", Ip: netip.MustParseAddr("10.0.0.7"), Domain: "home.lan"},
+	}
+
+	g := New(hosts, networks, Options{Serial: 7, Domain: "Fallback.Lan"})
+
+	zones := g.ForwardZones()
+	if len(zones) != 2 {
+		t.Fatalf("got %d zones, want 2", len(zones))
+	}
+
+	want := map[string][]ZoneRecord{
+		"fallback.lan": {
+			{Name: "printer", Type: "A", Value: "10.0.0.6"},
+		},
+		"home.lan": {
+			{Name: "ns", Type: "A", Value: "10.0.0.1"},
+			{Name: "nas", Type: "A", Value: "10.0.0.5"},
+		},
+	}
+	for _, zone := range zones {
+		records := want[zone.Origin]
+		if len(zone.Records) != len(records) {
+			t.Errorf("%s: got records %v, want %v", zone.Origin, zone.Records, records)
+			continue
+		}
+		for i := range records {
+			if zone.Records[i] != records[i] {
+				t.Errorf("%s: got records %v, want %v", zone.Origin, zone.Records, records)
+				break
+			}
+		}
+		if zone.Serial != 7 || zone.Nameserver != "ns."+zone.Origin {
+			t.Errorf("%s: got serial %d nameserver %s", zone.Origin, zone.Serial, zone.Nameserver)
+		}
+	}
+
+	// the client named ns must not get a second address record or a ptr
+	for _, zone := range g.ReverseZones() {
+		for _, record := range zone.Records {
+			if record.Value == "ns.home.lan." {
+				t.Errorf("%s: unexpected ptr %v", zone.Origin, record)
+			}
+		}
+	}
+
+}
+
+func TestReverseZones(t *testing.T) {
+
+	networks := []omada.OmadaNetwork{
+		{Name: "lan", Domain: "home.lan", Subnet: "10.0.4.1/23"},
+	}
+	hosts := []omada.HostNetwork{
+		{DnsName: "a", Ip: netip.MustParseAddr("10.0.4.10"), Domain: "home.lan"},
+		{DnsName: "b", Ip: netip.MustParseAddr("10.0.5.20"), Domain: "home.lan"},
+	}
+
+	zones := New(hosts, networks, Options{Serial: 1}).ReverseZones()
+
+	got := make(map[string][]ZoneRecord)
+	for _, zone := range zones {
+		got[zone.Origin] = zone.Records
+	}
+	want := map[string][]ZoneRecord{
+		"4.0.10.in-addr.arpa": {{Name: "10", Type: "PTR", Value: "a.home.lan."}},
+		"5.0.10.in-addr.arpa": {{Name: "20", Type: "PTR", Value: "b.home.lan."}},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got zones %v, want %v", got, want)
+	}
+	for origin, records := range want {
+		if len(got[origin]) != 1 || got[origin][0] != records[0] {
+			t.Errorf("%s: got %v, want %v", origin, got[origin], records)
+		}
+	}
+
+}
@@ -0,0 +1,69 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Healthy reports an error when the records have never been loaded or the
+// last successful refresh is more than three refresh intervals old.
+func (s *Server) Healthy() error {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.lastRefresh.IsZero() {
+		if s.lastErr != nil {
+			return fmt.Errorf("records not loaded: %w", s.lastErr)
+		}
+		return fmt.Errorf("records not loaded")
+	}
+
+	if age := time.Since(s.lastRefresh); age > 3*s.opts.Refresh {
+		return fmt.Errorf("records are %s old, last error: %v", age.Round(time.Second), s.lastErr)
+	}
+
+	return nil
+
+}
+
+func (s *Server) Describe(ch chan<- *prometheus.Desc) {
+	s.queries.Describe(ch)
+	s.upstreamErrs.Describe(ch)
+	s.refreshErrs.Describe(ch)
+	s.records.Describe(ch)
+	s.refreshedTime.Describe(ch)
+}
+
+func (s *Server) Collect(ch chan<- prometheus.Metric) {
+	s.queries.Collect(ch)
+	s.upstreamErrs.Collect(ch)
+	s.refreshErrs.Collect(ch)
+	s.records.Collect(ch)
+	s.refreshedTime.Collect(ch)
+}
+
+// HTTPHandler serves /healthz and /metrics. The server can also be
+// registered with an existing prometheus registry instead.
+func (s *Server) HTTPHandler() http.Handler {
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(s)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Healthy(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+
+}
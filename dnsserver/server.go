@@ -0,0 +1,559 @@
+// Package dnsserver is a small dns server for sites that do not want to run
+// BIND. It answers A, AAAA, PTR and SRV queries for <DnsName>.<domain> from
+// a periodically refreshed copy of the controller's clients and devices, and
+// forwards everything else upstream.
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Source returns the hosts to answer for. ControllerSource reads them from a
+// controller, tests can pass a SourceFunc with fixed records.
+type Source interface {
+	Records(ctx context.Context) ([]omadadns.HostRecord, error)
+}
+
+type SourceFunc func(ctx context.Context) ([]omadadns.HostRecord, error)
+
+func (f SourceFunc) Records(ctx context.Context) ([]omadadns.HostRecord, error) {
+	return f(ctx)
+}
+
+// ControllerSource reads the clients and devices of every site the
+// controller user can access. Domain is used for hosts whose network has no
+// domain set.
+func ControllerSource(c *omada.Controller, domain string) Source {
+	return SourceFunc(func(ctx context.Context) ([]omadadns.HostRecord, error) {
+		generator, err := omadadns.FromAllSites(c.WithContext(ctx), omadadns.Options{Domain: domain})
+		if err != nil {
+			return nil, err
+		}
+		return generator.Records(), nil
+	})
+}
+
+// Service publishes an SRV record, such as _ssh._tcp.<domain>, pointing at
+// the named hosts in every domain they are found in.
+type Service struct {
+	Name     string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+	Hosts    []string
+}
+
+type Options struct {
+	// Addr is listened on for both udp and tcp. Defaults to :53.
+	Addr string
+
+	// Upstream servers, as host:port, queries outside the local domains are
+	// forwarded to in order. With none they are refused.
+	Upstream []string
+
+	Ttl      uint32
+	Refresh  time.Duration
+	Timeout  time.Duration
+	Services []Service
+
+	OnError func(err error)
+}
+
+type Server struct {
+	source Source
+	opts   Options
+	client *dns.Client
+
+	mu          sync.RWMutex
+	index       *index
+	lastRefresh time.Time
+	lastErr     error
+
+	queries       *prometheus.CounterVec
+	upstreamErrs  *prometheus.CounterVec
+	refreshErrs   prometheus.Counter
+	records       prometheus.Gauge
+	refreshedTime prometheus.Gauge
+}
+
+func New(source Source, opts Options) *Server {
+
+	if opts.Addr == "" {
+		opts.Addr = ":53"
+	}
+	if opts.Ttl == 0 {
+		opts.Ttl = 60
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	return &Server{
+		source: source,
+		opts:   opts,
+		client: &dns.Client{Net: "udp", Timeout: opts.Timeout},
+		index:  newIndex(nil, nil, 0),
+
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "omada_dns",
+			Name:      "queries_total",
+			Help:      "DNS queries answered, by query type and result.",
+		}, []string{"type", "result"}),
+		upstreamErrs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "omada_dns",
+			Name:      "upstream_errors_total",
+			Help:      "Failed exchanges with upstream servers.",
+		}, []string{"upstream"}),
+		refreshErrs: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "omada_dns",
+			Name:      "refresh_errors_total",
+			Help:      "Failed refreshes of the record cache.",
+		}),
+		records: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "omada_dns",
+			Name:      "records",
+			Help:      "Hosts currently answered for.",
+		}),
+		refreshedTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "omada_dns",
+			Name:      "last_refresh_timestamp_seconds",
+			Help:      "Time of the last successful refresh of the record cache.",
+		}),
+	}
+}
+
+// Refresh reloads the records from the source. On failure the previous
+// records keep being served.
+func (s *Server) Refresh(ctx context.Context) error {
+
+	records, err := s.source.Records(ctx)
+	if err != nil {
+		s.refreshErrs.Inc()
+		s.mu.Lock()
+		s.lastErr = err
+		s.mu.Unlock()
+		return err
+	}
+
+	idx := newIndex(records, s.opts.Services, uint32(time.Now().Unix()))
+
+	s.mu.Lock()
+	s.index = idx
+	s.lastRefresh = time.Now()
+	s.lastErr = nil
+	s.mu.Unlock()
+
+	s.records.Set(float64(len(records)))
+	s.refreshedTime.SetToCurrentTime()
+
+	return nil
+
+}
+
+// ListenAndServe refreshes the records and serves udp and tcp on Addr until
+// ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+
+	if err := s.Refresh(ctx); err != nil {
+		s.error(fmt.Errorf("initial refresh: %w", err))
+	}
+
+	started := make(chan struct{}, 2)
+	notify := func() { started <- struct{}{} }
+	servers := []*dns.Server{
+		{Addr: s.opts.Addr, Net: "udp", Handler: s, NotifyStartedFunc: notify},
+		{Addr: s.opts.Addr, Net: "tcp", Handler: s, NotifyStartedFunc: notify},
+	}
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			errs <- server.ListenAndServe()
+		}(server)
+	}
+
+	// shutting down a server that has not started yet is a no-op, so wait
+	// for both before anything can stop them
+	var err error
+	for running := 0; running < len(servers) && err == nil; {
+		select {
+		case <-started:
+			running++
+		case err = <-errs:
+		}
+	}
+	if err != nil {
+		for _, server := range servers {
+			server.Shutdown()
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(s.opts.Refresh)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case err = <-errs:
+			break loop
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.error(err)
+			}
+		}
+	}
+
+	for _, server := range servers {
+		server.Shutdown()
+	}
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+
+}
+
+// ServeDNS implements dns.Handler.
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+
+	m := s.Resolve(ctx, r)
+
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := dns.MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		m.Truncate(size)
+	}
+
+	w.WriteMsg(m)
+
+}
+
+// Resolve answers a single query. It is what ServeDNS uses, and lets the
+// server be used and tested without opening any sockets.
+func (s *Server) Resolve(ctx context.Context, r *dns.Msg) *dns.Msg {
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	if len(r.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		s.count(0, "formerr")
+		return m
+	}
+	q := r.Question[0]
+
+	s.mu.RLock()
+	idx := s.index
+	s.mu.RUnlock()
+
+	name := strings.ToLower(q.Name)
+	if idx.local(name) {
+		m.Authoritative = true
+		m.RecursionAvailable = len(s.opts.Upstream) > 0
+		m.Answer, m.Extra = idx.answer(name, q.Name, q.Qtype, s.opts.Ttl)
+		if len(m.Answer) > 0 {
+			s.count(q.Qtype, "local")
+			return m
+		}
+
+		// negative answers carry the zone's soa so resolvers can cache them,
+		// rfc 2308
+		if zone := idx.zone(name); zone != "" {
+			m.Ns = []dns.RR{idx.soa(zone, zone, s.opts.Ttl)}
+		}
+		if !idx.exists(name) {
+			m.Rcode = dns.RcodeNameError
+			s.count(q.Qtype, "nxdomain")
+			return m
+		}
+		s.count(q.Qtype, "nodata")
+		return m
+	}
+
+	if len(s.opts.Upstream) == 0 {
+		m.Rcode = dns.RcodeRefused
+		s.count(q.Qtype, "refused")
+		return m
+	}
+
+	if resp := s.forward(ctx, r); resp != nil {
+		s.count(q.Qtype, "forwarded")
+		return resp
+	}
+
+	m.Rcode = dns.RcodeServerFailure
+	s.count(q.Qtype, "servfail")
+	return m
+
+}
+
+func (s *Server) forward(ctx context.Context, r *dns.Msg) *dns.Msg {
+
+	for _, upstream := range s.opts.Upstream {
+		resp, _, err := s.client.ExchangeContext(ctx, r, upstream)
+		if err == nil && resp.Truncated {
+			tcp := &dns.Client{Net: "tcp", Timeout: s.opts.Timeout}
+			resp, _, err = tcp.ExchangeContext(ctx, r, upstream)
+		}
+		if err != nil {
+			s.upstreamErrs.WithLabelValues(upstream).Inc()
+			s.error(fmt.Errorf("forward to %s: %w", upstream, err))
+			continue
+		}
+		resp.Id = r.Id
+		return resp
+	}
+
+	return nil
+
+}
+
+func (s *Server) count(qtype uint16, result string) {
+	s.queries.WithLabelValues(dns.TypeToString[qtype], result).Inc()
+}
+
+func (s *Server) error(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+// index is an immutable snapshot of the records, swapped as a whole on
+// every refresh.
+type index struct {
+	addrs   map[string][]netip.Addr
+	ptrs    map[string][]string
+	srvs    map[string][]*dns.SRV
+	domains []string
+	serial  uint32
+
+	// names holds every name with records and the empty non-terminals
+	// between them and their zone, such as _tcp.<domain>
+	names map[string]bool
+}
+
+func newIndex(records []omadadns.HostRecord, services []Service, serial uint32) *index {
+
+	idx := &index{
+		addrs:  make(map[string][]netip.Addr),
+		ptrs:   make(map[string][]string),
+		srvs:   make(map[string][]*dns.SRV),
+		serial: serial,
+		names:  make(map[string]bool),
+	}
+
+	domains := make(map[string]bool)
+	for _, record := range records {
+		if record.Name == "" || record.Domain == "" || !record.Addr.IsValid() {
+			continue
+		}
+
+		fqdn := dns.Fqdn(strings.ToLower(record.Fqdn()))
+		idx.addrs[fqdn] = append(idx.addrs[fqdn], record.Addr)
+		domains[dns.Fqdn(strings.ToLower(record.Domain))] = true
+
+		if reverse, err := dns.ReverseAddr(record.Addr.String()); err == nil {
+			idx.ptrs[reverse] = append(idx.ptrs[reverse], fqdn)
+		}
+	}
+
+	for domain := range domains {
+		idx.domains = append(idx.domains, domain)
+
+		for _, service := range services {
+			name := dns.Fqdn(strings.ToLower(service.Name)) + domain
+			for _, host := range service.Hosts {
+				target := dns.Fqdn(strings.ToLower(host)) + domain
+				if _, ok := idx.addrs[target]; !ok {
+					continue
+				}
+				idx.srvs[name] = append(idx.srvs[name], &dns.SRV{
+					Priority: service.Priority,
+					Weight:   service.Weight,
+					Port:     service.Port,
+					Target:   target,
+				})
+			}
+		}
+	}
+
+	for name := range idx.addrs {
+		idx.addName(name)
+	}
+	for name := range idx.ptrs {
+		idx.addName(name)
+	}
+	for name := range idx.srvs {
+		idx.addName(name)
+	}
+
+	return idx
+
+}
+
+// addName adds name and its ancestors up to, but not including, its zone.
+func (idx *index) addName(name string) {
+
+	zone := idx.zone(name)
+	for name != zone && !idx.names[name] {
+		idx.names[name] = true
+		next, end := dns.NextLabel(name, 0)
+		if end {
+			return
+		}
+		name = name[next:]
+	}
+
+}
+
+// zone returns the apex of the zone name is answered from: the longest
+// matching domain, or for reverse names the /24 or /64 the address is in.
+func (idx *index) zone(name string) string {
+
+	zone := ""
+	for _, domain := range idx.domains {
+		if dns.IsSubDomain(domain, name) && len(domain) > len(zone) {
+			zone = domain
+		}
+	}
+	if zone != "" {
+		return zone
+	}
+
+	labels := dns.SplitDomainName(name)
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa.") && len(labels) == 6:
+		return dns.Fqdn(strings.Join(labels[1:], "."))
+	case strings.HasSuffix(name, ".ip6.arpa.") && len(labels) == 34:
+		return dns.Fqdn(strings.Join(labels[16:], "."))
+	}
+
+	return ""
+
+}
+
+func (idx *index) soa(zone string, owner string, ttl uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: owner, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      "ns." + zone,
+		Mbox:    "hostmaster." + zone,
+		Serial:  idx.serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  604800,
+		Minttl:  ttl,
+	}
+}
+
+// local reports whether name is answered from the index rather than
+// forwarded. Reverse names are only local when a host has that address.
+func (idx *index) local(name string) bool {
+
+	if _, ok := idx.ptrs[name]; ok {
+		return true
+	}
+
+	for _, domain := range idx.domains {
+		if dns.IsSubDomain(domain, name) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func (idx *index) exists(name string) bool {
+
+	if idx.names[name] {
+		return true
+	}
+
+	for _, domain := range idx.domains {
+		if name == domain {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// answer looks up the lower cased name but writes the records with owner, so
+// the case of the question is kept.
+func (idx *index) answer(name string, owner string, qtype uint16, ttl uint32) ([]dns.RR, []dns.RR) {
+
+	header := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: owner, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+	}
+
+	var answer []dns.RR
+	var extra []dns.RR
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeANY:
+		answer = idx.addressRecords(name, owner, qtype, ttl)
+	case dns.TypePTR:
+		for _, target := range idx.ptrs[name] {
+			answer = append(answer, &dns.PTR{Hdr: header(dns.TypePTR), Ptr: target})
+		}
+	case dns.TypeSOA:
+		if zone := idx.zone(name); zone == name {
+			answer = append(answer, idx.soa(zone, owner, ttl))
+		}
+	case dns.TypeSRV:
+		for _, srv := range idx.srvs[name] {
+			rr := *srv
+			rr.Hdr = header(dns.TypeSRV)
+			answer = append(answer, &rr)
+			extra = append(extra, idx.addressRecords(srv.Target, srv.Target, dns.TypeANY, ttl)...)
+		}
+	}
+
+	return answer, extra
+
+}
+
+func (idx *index) addressRecords(name string, owner string, qtype uint16, ttl uint32) []dns.RR {
+
+	var rrs []dns.RR
+	for _, addr := range idx.addrs[name] {
+		if addr.Is4() && (qtype == dns.TypeA || qtype == dns.TypeANY) {
+			rrs = append(rrs, &dns.A{
+				Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   addr.AsSlice(),
+			})
+		}
+		if addr.Is6() && (qtype == dns.TypeAAAA || qtype == dns.TypeANY) {
+			rrs = append(rrs, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: owner, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+				AAAA: addr.AsSlice(),
+			})
+		}
+	}
+
+	return rrs
+
+}
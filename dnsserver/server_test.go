@@ -0,0 +1,216 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+	"github.com/miekg/dns"
+)
+
+func testServer(t *testing.T) *Server {
+
+	records := []omadadns.HostRecord{
+		{Name: "nas", Domain: "home.lan", Addr: netip.MustParseAddr("10.0.0.5")},
+		{Name: "nas", Domain: "home.lan", Addr: netip.MustParseAddr("2001:db8::5")},
+		{Name: "printer", Domain: "home.lan", Addr: netip.MustParseAddr("10.0.0.6")},
+	}
+	source := SourceFunc(func(ctx context.Context) ([]omadadns.HostRecord, error) {
+		return records, nil
+	})
+
+	s := New(source, Options{
+		Ttl:      30,
+		Services: []Service{{Name: "_ssh._tcp", Port: 22, Hosts: []string{"nas"}}},
+	})
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+
+}
+
+func TestResolve(t *testing.T) {
+
+	s := testServer(t)
+
+	tests := []struct {
+		name   string
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer []string
+		soa    bool
+	}{
+		{"a", "nas.home.lan.", dns.TypeA, dns.RcodeSuccess, []string{"10.0.0.5"}, false},
+		{"aaaa", "nas.home.lan.", dns.TypeAAAA, dns.RcodeSuccess, []string{"2001:db8::5"}, false},
+		{"case is kept", "NAS.Home.Lan.", dns.TypeA, dns.RcodeSuccess, []string{"10.0.0.5"}, false},
+		{"ptr", "5.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess, []string{"nas.home.lan."}, false},
+		{"srv", "_ssh._tcp.home.lan.", dns.TypeSRV, dns.RcodeSuccess, []string{"nas.home.lan."}, false},
+		{"soa at the apex", "home.lan.", dns.TypeSOA, dns.RcodeSuccess, []string{"ns.home.lan."}, false},
+		{"nxdomain", "missing.home.lan.", dns.TypeA, dns.RcodeNameError, nil, true},
+		{"nodata for another type", "printer.home.lan.", dns.TypeAAAA, dns.RcodeSuccess, nil, true},
+		{"nodata at the apex", "home.lan.", dns.TypeA, dns.RcodeSuccess, nil, true},
+		{"nodata for an empty non-terminal", "_tcp.home.lan.", dns.TypeSRV, dns.RcodeSuccess, nil, true},
+		{"nxdomain below a host", "x.nas.home.lan.", dns.TypeA, dns.RcodeNameError, nil, true},
+		{"nodata for a reverse name", "5.0.0.10.in-addr.arpa.", dns.TypeA, dns.RcodeSuccess, nil, true},
+		{"refused without upstreams", "example.com.", dns.TypeA, dns.RcodeRefused, nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := new(dns.Msg)
+			r.SetQuestion(test.qname, test.qtype)
+			m := s.Resolve(context.Background(), r)
+
+			if m.Rcode != test.rcode {
+				t.Fatalf("got rcode %s, want %s", dns.RcodeToString[m.Rcode], dns.RcodeToString[test.rcode])
+			}
+
+			var got []string
+			for _, rr := range m.Answer {
+				if rr.Header().Name != test.qname {
+					t.Errorf("got owner %s, want %s", rr.Header().Name, test.qname)
+				}
+				switch rr := rr.(type) {
+				case *dns.A:
+					got = append(got, rr.A.String())
+				case *dns.AAAA:
+					got = append(got, rr.AAAA.String())
+				case *dns.PTR:
+					got = append(got, rr.Ptr)
+				case *dns.SRV:
+					got = append(got, rr.Target)
+				case *dns.SOA:
+					got = append(got, rr.Ns)
+				}
+			}
+			if len(got) != len(test.answer) {
+				t.Fatalf("got answer %v, want %v", got, test.answer)
+			}
+			for i := range got {
+				if got[i] != test.answer[i] {
+					t.Fatalf("got answer %v, want %v", got, test.answer)
+				}
+			}
+
+			if !test.soa {
+				if len(m.Ns) != 0 {
+					t.Errorf("unexpected authority %v", m.Ns)
+				}
+				return
+			}
+			if len(m.Ns) != 1 {
+				t.Fatalf("got authority %v, want the zone soa", m.Ns)
+			}
+			soa, ok := m.Ns[0].(*dns.SOA)
+			if !ok || soa.Minttl != 30 || soa.Serial == 0 {
+				t.Errorf("got authority %v", m.Ns[0])
+			}
+		})
+	}
+
+}
+
+func TestResolveReverseSoa(t *testing.T) {
+
+	s := testServer(t)
+
+	r := new(dns.Msg)
+	r.SetQuestion("5.0.0.10.in-addr.arpa.", dns.TypeA)
+	m := s.Resolve(context.Background(), r)
+
+	if len(m.Ns) != 1 || m.Ns[0].Header().Name != "0.0.10.in-addr.arpa." {
+		t.Errorf("got authority %v, want the 0.0.10.in-addr.arpa soa", m.Ns)
+	}
+
+}
+
+func TestServeDNS(t *testing.T) {
+
+	s := testServer(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: s, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not start")
+	}
+
+	client := &dns.Client{Timeout: 5 * time.Second}
+
+	r := new(dns.Msg)
+	r.SetQuestion("nas.home.lan.", dns.TypeA)
+	m, _, err := client.Exchange(r, conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Authoritative || len(m.Answer) != 1 || m.Answer[0].(*dns.A).A.String() != "10.0.0.5" {
+		t.Errorf("got %v", m)
+	}
+
+	r.SetQuestion("missing.home.lan.", dns.TypeAAAA)
+	m, _, err = client.Exchange(r, conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rcode != dns.RcodeNameError || len(m.Ns) != 1 {
+		t.Errorf("got %v", m)
+	}
+
+}
+
+func TestControllerSourceUsesContext(t *testing.T) {
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, "/login"):
+			result = map[string]string{"token": "token"}
+		case strings.HasSuffix(path, "/users/current"):
+			result = map[string]interface{}{"privilege": map[string]interface{}{
+				"sites": []omada.Sites{{Name: "Home", Key: "site1"}},
+			}}
+		default:
+			requests++
+			result = map[string]interface{}{"data": []interface{}{}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := omada.New(server.URL)
+	if err := c.Login("user", "pass", "Home"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ControllerSource(&c, "home.lan").Records(ctx); err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests after the context was cancelled", requests)
+	}
+
+}
@@ -0,0 +1,233 @@
+package dnsupdate
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+	"github.com/miekg/dns"
+)
+
+// Sync replaces the set of hosts the updater publishes and sends updates for
+// every name whose records changed since the last sync.
+func (u *Updater) Sync(ctx context.Context, records []omadadns.HostRecord) error {
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	hosts := make(map[string]omadadns.HostRecord)
+	for _, record := range records {
+		hosts[hostKey(record)] = record
+	}
+	u.hosts = hosts
+
+	return u.applyAll(ctx)
+
+}
+
+// SyncController publishes the clients and devices of every site the
+// controller user can access.
+func (u *Updater) SyncController(ctx context.Context, c *omada.Controller) error {
+
+	generator, err := omadadns.FromAllSites(c.WithContext(ctx), omadadns.Options{Domain: u.opts.Domain})
+	if err != nil {
+		return err
+	}
+
+	u.SetNetworks(generator.Networks())
+	return u.Sync(ctx, generator.Records())
+
+}
+
+// SetNetworks sets the networks HandleEvent uses to find the domain of a
+// client or device. SyncController sets them itself.
+func (u *Updater) SetNetworks(networks []omada.OmadaNetwork) {
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.resolver = omada.NewNetworkResolver(networks)
+
+}
+
+// HandleEvent applies a single watcher event, so records follow clients as
+// they join, leave and change address without waiting for the next full
+// sync. It can be passed straight to WatchOptions.OnEvent.
+//
+// DeviceOffline is ignored. Devices stay adopted while they are
+// disconnected and SyncController keeps publishing them, so removing their
+// records here would only bring them back on the next sync. Devices removed
+// from the controller are dropped by that sync.
+func (u *Updater) HandleEvent(event omada.WatchEvent) {
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch event.Type {
+	case omada.ClientJoined, omada.ClientIPChanged, omada.ClientRoamed:
+		record := u.hostRecord(omada.HostKindClient, event.Client.DnsName, event.Client.MAC, event.Client.Ip)
+		u.hosts[hostKey(record)] = record
+	case omada.ClientLeft:
+		delete(u.hosts, hostKey(u.hostRecord(omada.HostKindClient, event.Client.DnsName, event.Client.MAC, event.Client.Ip)))
+	case omada.DeviceOnline:
+		record := u.hostRecord(omada.HostKindDevice, event.Device.DnsName, event.Device.Mac, event.Device.IP)
+		u.hosts[hostKey(record)] = record
+	default:
+		return
+	}
+
+	if err := u.applyAll(context.Background()); err != nil {
+		u.error(err)
+	}
+
+}
+
+// Run calls SyncController every interval until ctx is cancelled. Names
+// that did not change are not sent again, so each run only costs the
+// updates for what changed on the controller.
+func (u *Updater) Run(ctx context.Context, c *omada.Controller, interval time.Duration) error {
+
+	for {
+		if err := u.SyncController(ctx, c); err != nil {
+			u.error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+}
+
+func (u *Updater) hostRecord(kind string, name string, mac string, ip string) omadadns.HostRecord {
+
+	record := omadadns.HostRecord{
+		Kind:   kind,
+		Name:   name,
+		Mac:    mac,
+		Domain: strings.TrimSuffix(u.opts.Domain, "."),
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return record
+	}
+	record.Addr = addr.Unmap()
+
+	if u.resolver != nil {
+		if network, ok := u.resolver.Lookup(record.Addr); ok && network.Domain != "" {
+			record.Domain = strings.TrimSuffix(network.Domain, ".")
+		}
+	}
+
+	return record
+
+}
+
+func (u *Updater) applyAll(ctx context.Context) error {
+
+	if !u.loaded {
+		if err := u.load(); err != nil {
+			return err
+		}
+	}
+
+	desired := u.desired()
+
+	names := make(map[string]bool)
+	for name := range desired {
+		names[name] = true
+	}
+	for name := range u.owned {
+		names[name] = true
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var errs []error
+	for _, name := range sorted {
+		if err := u.apply(ctx, name, desired[name]); err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// desired returns the A, AAAA and PTR records every published host should
+// have, keyed by owner name. Names outside the configured zones are left
+// out.
+func (u *Updater) desired() map[string][]dns.RR {
+
+	desired := make(map[string][]dns.RR)
+	add := func(rr dns.RR) {
+		name := rr.Header().Name
+		for _, existing := range desired[name] {
+			if rrKey(existing) == rrKey(rr) {
+				return
+			}
+		}
+		desired[name] = append(desired[name], rr)
+	}
+
+	for _, host := range u.hosts {
+		if host.Name == "" || host.Domain == "" || !host.Addr.IsValid() {
+			continue
+		}
+
+		fqdn := dns.Fqdn(strings.ToLower(host.Fqdn()))
+		if u.zoneFor(fqdn) != "" {
+			add(addressRecord(fqdn, host, u.opts.Ttl))
+		}
+
+		reverse, err := dns.ReverseAddr(host.Addr.String())
+		if err != nil || u.zoneFor(reverse) == "" {
+			continue
+		}
+		add(&dns.PTR{
+			Hdr: dns.RR_Header{Name: reverse, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: u.opts.Ttl},
+			Ptr: fqdn,
+		})
+	}
+
+	return desired
+
+}
+
+func addressRecord(fqdn string, host omadadns.HostRecord, ttl uint32) dns.RR {
+
+	if host.Addr.Is4() {
+		return &dns.A{
+			Hdr: dns.RR_Header{Name: fqdn, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   host.Addr.AsSlice(),
+		}
+	}
+
+	return &dns.AAAA{
+		Hdr:  dns.RR_Header{Name: fqdn, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+		AAAA: host.Addr.AsSlice(),
+	}
+
+}
+
+func hostKey(record omadadns.HostRecord) string {
+	if record.Mac == "" {
+		return record.Kind + "/" + record.Fqdn()
+	}
+	return record.Kind + "/" + strings.ToUpper(strings.ReplaceAll(record.Mac, ":", "-"))
+}
@@ -0,0 +1,405 @@
+// Package dnsupdate keeps A, AAAA and PTR records for Omada clients and
+// devices in sync on an authoritative server such as BIND, Knot or PowerDNS,
+// using rfc 2136 dynamic updates signed with TSIG.
+//
+// Every name the updater creates also gets a TXT ownership record. Names are
+// only ever changed or deleted when that record is present, so records added
+// by hand or by other tools in the same zone are left alone.
+package dnsupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+	"github.com/miekg/dns"
+)
+
+var ErrNotOwned = errors.New("name exists and is not owned by this updater")
+
+type Options struct {
+	// Server is the host:port updates and zone transfers are sent to.
+	Server string
+	// Net is "tcp" or "udp". Defaults to tcp.
+	Net string
+
+	// Zones the updater may write to, forward and reverse. Names outside
+	// every zone are skipped.
+	Zones []string
+
+	TsigName      string
+	TsigSecret    string
+	TsigAlgorithm string
+
+	// Domain is used for hosts whose network has no domain set.
+	Domain string
+	Ttl    uint32
+
+	// Owner is written into the ownership TXT records, so several updaters
+	// can share a zone without touching each other's names.
+	Owner string
+
+	// DryRun reports changes through OnChange without sending them.
+	DryRun  bool
+	Timeout time.Duration
+
+	OnChange func(Change)
+	OnError  func(err error)
+}
+
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+type Change struct {
+	Action   ChangeAction
+	Zone     string
+	Name     string
+	Records  []dns.RR
+	Previous []dns.RR
+	DryRun   bool
+}
+
+func (c Change) String() string {
+
+	var values []string
+	for _, rr := range c.Records {
+		values = append(values, rrValue(rr))
+	}
+	if c.Action == ChangeDelete {
+		for _, rr := range c.Previous {
+			values = append(values, rrValue(rr))
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s", c.Action, c.Name, strings.Join(values, " "))
+
+}
+
+type Updater struct {
+	opts     Options
+	client   *dns.Client
+	ownerTxt string
+
+	mu       sync.Mutex
+	hosts    map[string]omadadns.HostRecord
+	owned    map[string][]dns.RR
+	loaded   bool
+	resolver *omada.NetworkResolver
+}
+
+func New(opts Options) (*Updater, error) {
+
+	if opts.Server == "" {
+		return nil, fmt.Errorf("dns server is required")
+	}
+	if len(opts.Zones) == 0 {
+		return nil, fmt.Errorf("at least one zone is required")
+	}
+	if opts.Net == "" {
+		opts.Net = "tcp"
+	}
+	if opts.Ttl == 0 {
+		opts.Ttl = 300
+	}
+	if opts.Owner == "" {
+		opts.Owner = "default"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.TsigAlgorithm == "" {
+		opts.TsigAlgorithm = dns.HmacSHA256
+	}
+	opts.TsigAlgorithm = dns.Fqdn(strings.ToLower(opts.TsigAlgorithm))
+
+	for i, zone := range opts.Zones {
+		opts.Zones[i] = dns.Fqdn(strings.ToLower(zone))
+	}
+	// longest zone first so names land in the most specific one
+	sort.Slice(opts.Zones, func(i, j int) bool {
+		return dns.CountLabel(opts.Zones[i]) > dns.CountLabel(opts.Zones[j])
+	})
+
+	client := &dns.Client{Net: opts.Net, Timeout: opts.Timeout}
+	if opts.TsigName != "" {
+		opts.TsigName = dns.Fqdn(strings.ToLower(opts.TsigName))
+		client.TsigSecret = map[string]string{opts.TsigName: opts.TsigSecret}
+	}
+
+	return &Updater{
+		opts:     opts,
+		client:   client,
+		ownerTxt: fmt.Sprintf("heritage=go-omada,owner=%s", opts.Owner),
+		hosts:    make(map[string]omadadns.HostRecord),
+		owned:    make(map[string][]dns.RR),
+	}, nil
+}
+
+// Load reads every zone with a zone transfer to find the names this updater
+// owns. The first sync runs it when it has not been called yet, otherwise
+// names owned from an earlier run that are gone from the controller would
+// never be deleted. Call it again to pick up changes made to the zones by
+// other means.
+func (u *Updater) Load() error {
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.load()
+
+}
+
+func (u *Updater) load() error {
+
+	owned := make(map[string][]dns.RR)
+	for _, zone := range u.opts.Zones {
+		names, err := u.transfer(zone)
+		if err != nil {
+			return fmt.Errorf("zone transfer of %s: %w", zone, err)
+		}
+		for name, rrs := range names {
+			owned[name] = rrs
+		}
+	}
+
+	u.owned = owned
+	u.loaded = true
+	return nil
+
+}
+
+func (u *Updater) transfer(zone string) (map[string][]dns.RR, error) {
+
+	m := new(dns.Msg)
+	m.SetAxfr(zone)
+	u.sign(m)
+
+	t := &dns.Transfer{TsigSecret: u.client.TsigSecret}
+	envelopes, err := t.In(m, u.opts.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string][]dns.RR)
+	ownedNames := make(map[string]bool)
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		for _, rr := range envelope.RR {
+			name := strings.ToLower(rr.Header().Name)
+			switch rr := rr.(type) {
+			case *dns.TXT:
+				if strings.Join(rr.Txt, "") == u.ownerTxt {
+					ownedNames[name] = true
+				}
+			case *dns.A, *dns.AAAA, *dns.PTR:
+				records[name] = append(records[name], rr)
+			}
+		}
+	}
+
+	owned := make(map[string][]dns.RR)
+	for name := range ownedNames {
+		owned[name] = records[name]
+	}
+
+	return owned, nil
+
+}
+
+func (u *Updater) sign(m *dns.Msg) {
+	if u.opts.TsigName != "" {
+		m.SetTsig(u.opts.TsigName, u.opts.TsigAlgorithm, 300, time.Now().Unix())
+	}
+}
+
+func (u *Updater) zoneFor(name string) string {
+	for _, zone := range u.opts.Zones {
+		if dns.IsSubDomain(zone, name) {
+			return zone
+		}
+	}
+	return ""
+}
+
+func (u *Updater) ownerRecord(name string) dns.RR {
+	return &dns.TXT{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: u.opts.Ttl},
+		Txt: []string{u.ownerTxt},
+	}
+}
+
+// apply brings one name from its owned records to the desired ones. Names
+// that are not owned yet are created on the condition that nothing else
+// uses them, if that fails they are retried as an update in case the
+// ownership record was added since the zones were loaded.
+func (u *Updater) apply(ctx context.Context, name string, desired []dns.RR) error {
+
+	previous, owned := u.owned[name]
+	if !owned && len(desired) == 0 {
+		return nil
+	}
+	if owned && sameRecords(previous, desired) {
+		return nil
+	}
+
+	change := Change{
+		Zone:     u.zoneFor(name),
+		Name:     name,
+		Records:  desired,
+		Previous: previous,
+		DryRun:   u.opts.DryRun,
+	}
+	switch {
+	case len(desired) == 0:
+		change.Action = ChangeDelete
+	case owned:
+		change.Action = ChangeUpdate
+	default:
+		change.Action = ChangeCreate
+	}
+
+	if u.opts.DryRun {
+		u.changed(change)
+		return nil
+	}
+
+	err := u.send(ctx, change)
+	if errors.Is(err, ErrNotOwned) && change.Action == ChangeCreate {
+		change.Action = ChangeUpdate
+		err = u.send(ctx, change)
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotOwned) {
+			delete(u.owned, name)
+		}
+		return fmt.Errorf("%s %s: %w", change.Action, name, err)
+	}
+
+	if change.Action == ChangeDelete {
+		delete(u.owned, name)
+	} else {
+		u.owned[name] = desired
+	}
+	u.changed(change)
+
+	return nil
+
+}
+
+func (u *Updater) send(ctx context.Context, change Change) error {
+
+	owner := u.ownerRecord(change.Name)
+
+	m := new(dns.Msg)
+	m.SetUpdate(change.Zone)
+
+	switch change.Action {
+	case ChangeCreate:
+		m.NameNotUsed([]dns.RR{owner})
+		m.Insert(copyRecords(append([]dns.RR{owner}, change.Records...)))
+	case ChangeUpdate:
+		m.Used([]dns.RR{dns.Copy(owner)})
+		m.RemoveRRset(managedRRsets(change.Name))
+		m.Insert(copyRecords(append([]dns.RR{owner}, change.Records...)))
+	case ChangeDelete:
+		m.Used([]dns.RR{dns.Copy(owner)})
+		m.RemoveRRset(managedRRsets(change.Name))
+		m.Remove([]dns.RR{dns.Copy(owner)})
+	}
+	u.sign(m)
+
+	r, _, err := u.client.ExchangeContext(ctx, m, u.opts.Server)
+	if err != nil {
+		return err
+	}
+
+	switch r.Rcode {
+	case dns.RcodeSuccess:
+		return nil
+	case dns.RcodeYXDomain, dns.RcodeNXRrset:
+		return ErrNotOwned
+	default:
+		return fmt.Errorf("update refused: %s", dns.RcodeToString[r.Rcode])
+	}
+
+}
+
+func (u *Updater) changed(change Change) {
+	if u.opts.OnChange != nil {
+		u.opts.OnChange(change)
+	}
+}
+
+func (u *Updater) error(err error) {
+	if u.opts.OnError != nil {
+		u.opts.OnError(err)
+	}
+}
+
+func managedRRsets(name string) []dns.RR {
+	var rrs []dns.RR
+	for _, rrtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypePTR} {
+		rrs = append(rrs, &dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: rrtype}})
+	}
+	return rrs
+}
+
+func copyRecords(rrs []dns.RR) []dns.RR {
+	copied := make([]dns.RR, len(rrs))
+	for i, rr := range rrs {
+		copied[i] = dns.Copy(rr)
+	}
+	return copied
+}
+
+func sameRecords(a []dns.RR, b []dns.RR) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	values := make(map[string]int)
+	for _, rr := range a {
+		values[rrKey(rr)]++
+	}
+	for _, rr := range b {
+		values[rrKey(rr)]--
+	}
+	for _, n := range values {
+		if n != 0 {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+func rrKey(rr dns.RR) string {
+	return fmt.Sprintf("%d %d %s", rr.Header().Rrtype, rr.Header().Ttl, rrValue(rr))
+}
+
+func rrValue(rr dns.RR) string {
+	switch rr := rr.(type) {
+	case *dns.A:
+		return rr.A.String()
+	case *dns.AAAA:
+		return rr.AAAA.String()
+	case *dns.PTR:
+		return strings.ToLower(rr.Ptr)
+	}
+	return rr.String()
+}
@@ -0,0 +1,418 @@
+package dnsupdate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+	"github.com/miekg/dns"
+)
+
+const (
+	testTsigName   = "omada."
+	testTsigSecret = "c2VjcmV0c2VjcmV0c2VjcmV0"
+	testOwnerTxt   = "heritage=go-omada,owner=test"
+)
+
+// testZone is an authoritative server for home.lan and 0.0.10.in-addr.arpa
+// that only answers TSIG signed zone transfers and rfc 2136 updates.
+type testZone struct {
+	addr string
+
+	mu        sync.Mutex
+	records   []dns.RR
+	updates   []*dns.Msg
+	transfers int
+}
+
+func (z *testZone) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	switch {
+	case r.IsTsig() == nil || w.TsigStatus() != nil:
+		m.Rcode = dns.RcodeNotAuth
+	case r.Opcode == dns.OpcodeUpdate:
+		z.updates = append(z.updates, r.Copy())
+		m.Rcode = z.update(r)
+	case r.Question[0].Qtype == dns.TypeAXFR:
+		z.transfers++
+		soa := &dns.SOA{
+			Hdr:    dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+			Ns:     "ns.home.lan.",
+			Mbox:   "admin.home.lan.",
+			Serial: 1,
+		}
+		m.Answer = append(append([]dns.RR{soa}, z.records...), soa)
+	default:
+		m.Rcode = dns.RcodeRefused
+	}
+
+	if r.IsTsig() != nil {
+		m.SetTsig(testTsigName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	w.WriteMsg(m)
+
+}
+
+// update checks the prerequisites the updater uses, then applies the
+// update section
+func (z *testZone) update(r *dns.Msg) int {
+
+	for _, rr := range r.Answer {
+		h := rr.Header()
+		switch {
+		case h.Class == dns.ClassNONE && h.Rrtype == dns.TypeANY:
+			if len(z.find(h.Name, dns.TypeANY, nil)) != 0 {
+				return dns.RcodeYXDomain
+			}
+		case h.Class == dns.ClassINET:
+			if len(z.find(h.Name, h.Rrtype, rr)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for _, rr := range r.Ns {
+		h := rr.Header()
+		switch h.Class {
+		case dns.ClassANY:
+			z.remove(z.find(h.Name, h.Rrtype, nil))
+		case dns.ClassNONE:
+			z.remove(z.find(h.Name, h.Rrtype, rr))
+		default:
+			if len(z.find(h.Name, h.Rrtype, rr)) == 0 {
+				z.records = append(z.records, rr)
+			}
+		}
+	}
+
+	return dns.RcodeSuccess
+
+}
+
+// find returns the records with the owner name and type, and the same data
+// as match when it is set
+func (z *testZone) find(name string, rrtype uint16, match dns.RR) []dns.RR {
+
+	if match != nil {
+		match = dns.Copy(match)
+		match.Header().Class = dns.ClassINET
+	}
+
+	var found []dns.RR
+	for _, rr := range z.records {
+		h := rr.Header()
+		if !strings.EqualFold(h.Name, name) || (rrtype != dns.TypeANY && h.Rrtype != rrtype) {
+			continue
+		}
+		if match != nil && !dns.IsDuplicate(rr, match) {
+			continue
+		}
+		found = append(found, rr)
+	}
+
+	return found
+
+}
+
+func (z *testZone) remove(rrs []dns.RR) {
+
+	var kept []dns.RR
+	for _, rr := range z.records {
+		removed := false
+		for _, r := range rrs {
+			if rr == r {
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, rr)
+		}
+	}
+	z.records = kept
+
+}
+
+// values returns the data of the records with the owner name and type
+func (z *testZone) values(name string, rrtype uint16) []string {
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var values []string
+	for _, rr := range z.find(name, rrtype, nil) {
+		if txt, ok := rr.(*dns.TXT); ok {
+			values = append(values, strings.Join(txt.Txt, ""))
+			continue
+		}
+		values = append(values, rrValue(rr))
+	}
+	sort.Strings(values)
+
+	return values
+
+}
+
+func (z *testZone) sentUpdates() []*dns.Msg {
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	return append([]*dns.Msg{}, z.updates...)
+
+}
+
+func testServer(t *testing.T, records ...string) *testZone {
+
+	z := &testZone{}
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z.records = append(z.records, rr)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		Listener:   listener,
+		Handler:    z,
+		TsigSecret: map[string]string{testTsigName: testTsigSecret},
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			// the default rejects updates
+			return dns.MsgAccept
+		},
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not start")
+	}
+	z.addr = listener.Addr().String()
+
+	return z
+
+}
+
+func testUpdater(t *testing.T, z *testZone, opts Options) (*Updater, *[]Change) {
+
+	var changes []Change
+
+	opts.Server = z.addr
+	opts.Zones = []string{"home.lan", "0.0.10.in-addr.arpa"}
+	opts.TsigName = "omada"
+	opts.TsigSecret = testTsigSecret
+	opts.Domain = "home.lan"
+	opts.Ttl = 60
+	opts.Owner = "test"
+	opts.OnChange = func(change Change) {
+		changes = append(changes, change)
+	}
+
+	u, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u, &changes
+
+}
+
+func clientRecord(name string, mac string, ip string) omadadns.HostRecord {
+	return omadadns.HostRecord{Kind: omada.HostKindClient, Name: name, Mac: mac, Domain: "home.lan", Addr: netip.MustParseAddr(ip)}
+}
+
+func TestSyncCreatesOwnedNames(t *testing.T) {
+
+	z := testServer(t)
+	u, changes := testUpdater(t, z, Options{})
+
+	records := []omadadns.HostRecord{clientRecord("nas", "AA-BB-CC-00-00-01", "10.0.0.5")}
+	if err := u.Sync(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := z.values("nas.home.lan.", dns.TypeA); strings.Join(got, " ") != "10.0.0.5" {
+		t.Errorf("got a records %v", got)
+	}
+	if got := z.values("nas.home.lan.", dns.TypeTXT); strings.Join(got, " ") != testOwnerTxt {
+		t.Errorf("got txt records %v", got)
+	}
+	if got := z.values("5.0.0.10.in-addr.arpa.", dns.TypePTR); strings.Join(got, " ") != "nas.home.lan." {
+		t.Errorf("got ptr records %v", got)
+	}
+
+	// new names are only created when nothing else uses them
+	updates := z.sentUpdates()
+	if len(updates) != 2 {
+		t.Fatalf("got %d updates, want 2", len(updates))
+	}
+	for _, m := range updates {
+		if len(m.Answer) != 1 || m.Answer[0].Header().Class != dns.ClassNONE || m.Answer[0].Header().Rrtype != dns.TypeANY {
+			t.Errorf("got prerequisites %v, want name not used", m.Answer)
+		}
+	}
+	if len(*changes) != 2 || (*changes)[0].Action != ChangeCreate {
+		t.Errorf("got changes %v", *changes)
+	}
+
+	// nothing changed, so nothing is sent and the zones are not read again
+	if err := u.Sync(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+	if len(z.sentUpdates()) != 2 || z.transfers != 2 {
+		t.Errorf("got %d updates and %d transfers after an unchanged sync", len(z.sentUpdates()), z.transfers)
+	}
+
+}
+
+func TestSyncUpdatesAndDeletesOwnedNames(t *testing.T) {
+
+	// left behind by an earlier run, Load is not called so the first sync
+	// has to transfer the zones to find them
+	z := testServer(t,
+		`nas.home.lan. 60 IN A 10.0.0.5`,
+		`nas.home.lan. 60 IN TXT "`+testOwnerTxt+`"`,
+		`old.home.lan. 60 IN A 10.0.0.20`,
+		`old.home.lan. 60 IN TXT "`+testOwnerTxt+`"`,
+	)
+	u, changes := testUpdater(t, z, Options{})
+
+	records := []omadadns.HostRecord{clientRecord("nas", "AA-BB-CC-00-00-01", "10.0.0.7")}
+	if err := u.Sync(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := z.values("nas.home.lan.", dns.TypeA); strings.Join(got, " ") != "10.0.0.7" {
+		t.Errorf("got a records %v, want the old address replaced", got)
+	}
+	if got := z.values("old.home.lan.", dns.TypeANY); len(got) != 0 {
+		t.Errorf("got records %v, want old.home.lan deleted", got)
+	}
+
+	var actions []string
+	for _, change := range *changes {
+		actions = append(actions, string(change.Action)+" "+change.Name)
+	}
+	sort.Strings(actions)
+	want := "create 7.0.0.10.in-addr.arpa. delete old.home.lan. update nas.home.lan."
+	if got := strings.Join(actions, " "); got != want {
+		t.Errorf("got changes %q, want %q", got, want)
+	}
+
+	// updates and deletes require the ownership record and replace every
+	// managed rrset
+	for _, m := range z.sentUpdates() {
+		if m.Ns[0].Header().Name == "7.0.0.10.in-addr.arpa." {
+			continue
+		}
+		if len(m.Answer) != 1 || m.Answer[0].Header().Rrtype != dns.TypeTXT || m.Answer[0].Header().Class != dns.ClassINET {
+			t.Errorf("got prerequisites %v, want the ownership record", m.Answer)
+		}
+		removed := 0
+		for _, rr := range m.Ns {
+			if rr.Header().Class == dns.ClassANY {
+				removed++
+			}
+		}
+		if removed != 3 {
+			t.Errorf("got %d rrsets removed, want a, aaaa and ptr", removed)
+		}
+	}
+
+}
+
+func TestSyncLeavesUnownedNames(t *testing.T) {
+
+	z := testServer(t, `printer.home.lan. 60 IN A 10.0.0.99`)
+	u, _ := testUpdater(t, z, Options{})
+
+	records := []omadadns.HostRecord{clientRecord("printer", "AA-BB-CC-00-00-02", "10.0.0.6")}
+	err := u.Sync(context.Background(), records)
+	if !errors.Is(err, ErrNotOwned) {
+		t.Errorf("got error %v, want ErrNotOwned", err)
+	}
+
+	if got := z.values("printer.home.lan.", dns.TypeANY); strings.Join(got, " ") != "10.0.0.99" {
+		t.Errorf("got records %v, want the name left alone", got)
+	}
+
+}
+
+func TestSyncDryRun(t *testing.T) {
+
+	z := testServer(t,
+		`old.home.lan. 60 IN A 10.0.0.20`,
+		`old.home.lan. 60 IN TXT "`+testOwnerTxt+`"`,
+	)
+	u, changes := testUpdater(t, z, Options{DryRun: true})
+
+	records := []omadadns.HostRecord{clientRecord("nas", "AA-BB-CC-00-00-01", "10.0.0.5")}
+	if err := u.Sync(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(z.sentUpdates()) != 0 {
+		t.Errorf("dry run sent %d updates", len(z.sentUpdates()))
+	}
+	if len(*changes) != 3 {
+		t.Fatalf("got changes %v, want 3", *changes)
+	}
+	for _, change := range *changes {
+		if !change.DryRun {
+			t.Errorf("change %s is not marked as a dry run", change)
+		}
+	}
+
+}
+
+func TestHandleEvent(t *testing.T) {
+
+	z := testServer(t)
+	u, _ := testUpdater(t, z, Options{})
+
+	device := omada.Device{DnsName: "switch", Mac: "AA-BB-CC-00-00-03", IP: "10.0.0.2", StatusCategory: 1}
+	client := omada.Client{DnsName: "laptop", MAC: "AA-BB-CC-00-00-04", Ip: "10.0.0.8"}
+
+	u.HandleEvent(omada.WatchEvent{Type: omada.DeviceOnline, Device: &device})
+	u.HandleEvent(omada.WatchEvent{Type: omada.ClientJoined, Client: &client})
+	if len(z.values("switch.home.lan.", dns.TypeA)) != 1 || len(z.values("laptop.home.lan.", dns.TypeA)) != 1 {
+		t.Fatal("records were not created")
+	}
+
+	// an offline device keeps its records, a client that left loses them
+	u.HandleEvent(omada.WatchEvent{Type: omada.DeviceOffline, Device: &device})
+	u.HandleEvent(omada.WatchEvent{Type: omada.ClientLeft, Client: &client})
+
+	if got := z.values("switch.home.lan.", dns.TypeA); strings.Join(got, " ") != "10.0.0.2" {
+		t.Errorf("got device records %v", got)
+	}
+	if got := z.values("laptop.home.lan.", dns.TypeANY); len(got) != 0 {
+		t.Errorf("got client records %v, want them deleted", got)
+	}
+
+}
@@ -4,6 +4,14 @@ go 1.18
 
 require github.com/dougbw/go-omada v0.0.0-20221224171644-988966275196 // indirect
 
+require github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e // indirect
+
+require golang.org/x/crypto v0.24.0 // indirect
+
+require golang.org/x/net v0.26.0 // indirect
+
+require golang.org/x/text v0.16.0 // indirect
+
 replace (
      github.com/dougbw/go-omada => ../
 )
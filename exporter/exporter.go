@@ -0,0 +1,381 @@
+// Package exporter exposes omada devices, radios, switch ports, clients and
+// wans as prometheus metrics for every site the controller user can access.
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "omada"
+
+// every metric family labels site with the site id, so families can be
+// joined on it
+var (
+	deviceLabels = []string{"site", "name", "model", "mac", "type"}
+	radioLabels  = append(append([]string{}, deviceLabels...), "band")
+	portLabels   = append(append([]string{}, deviceLabels...), "port", "port_name")
+	clientLabels = []string{"site", "name", "mac", "ssid"}
+	wanLabels    = append(append([]string{}, deviceLabels...), "port", "port_name")
+)
+
+type Options struct {
+	// CacheTTL is how long results from the controller are reused between
+	// scrapes. Defaults to 60 seconds.
+	CacheTTL time.Duration
+	SiteIds  []string
+
+	// Relogin is called when a refresh fails because the session is no
+	// longer valid, after which the refresh is retried once. This keeps the
+	// exporter running past session expiry. Other errors, such as timeouts,
+	// are reported without logging in again.
+	Relogin func() error
+
+	// OnError is called when the ports of a switch or the wans of a gateway
+	// could not be read. The refresh carries on with the other devices and
+	// the device reports omada_device_scrape_success 0.
+	OnError func(err error)
+}
+
+type Collector struct {
+	c    *omada.Controller
+	opts Options
+
+	mu          sync.Mutex
+	lastRefresh time.Time
+	metrics     []prometheus.Metric
+	lastErr     error
+	lastTook    time.Duration
+
+	scrapeSuccess  *prometheus.Desc
+	scrapeDuration *prometheus.Desc
+
+	deviceUp       *prometheus.Desc
+	deviceCpu      *prometheus.Desc
+	deviceMem      *prometheus.Desc
+	deviceUptime   *prometheus.Desc
+	deviceUpload   *prometheus.Desc
+	deviceDownload *prometheus.Desc
+	deviceClients  *prometheus.Desc
+	deviceUpgrade  *prometheus.Desc
+	deviceScrape   *prometheus.Desc
+
+	radioClients *prometheus.Desc
+	radioTxUtil  *prometheus.Desc
+	radioRxUtil  *prometheus.Desc
+	radioInter   *prometheus.Desc
+	radioTxPower *prometheus.Desc
+
+	portUp       *prometheus.Desc
+	portSpeed    *prometheus.Desc
+	portPoe      *prometheus.Desc
+	portRx       *prometheus.Desc
+	portTx       *prometheus.Desc
+	portRxErrors *prometheus.Desc
+	portTxErrors *prometheus.Desc
+
+	clientRssi     *prometheus.Desc
+	clientSnr      *prometheus.Desc
+	clientDown     *prometheus.Desc
+	clientUp       *prometheus.Desc
+	clientUptime   *prometheus.Desc
+	clientActivity *prometheus.Desc
+
+	wanUp      *prometheus.Desc
+	wanOnline  *prometheus.Desc
+	wanActive  *prometheus.Desc
+	wanLatency *prometheus.Desc
+	wanLoss    *prometheus.Desc
+	wanRx      *prometheus.Desc
+	wanTx      *prometheus.Desc
+	wanRxRate  *prometheus.Desc
+	wanTxRate  *prometheus.Desc
+}
+
+func desc(subsystem string, name string, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+func NewCollector(c *omada.Controller, opts Options) *Collector {
+
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 60 * time.Second
+	}
+
+	return &Collector{
+		c:    c,
+		opts: opts,
+
+		scrapeSuccess:  desc("scrape", "success", "Whether the last refresh from the controller succeeded.", nil),
+		scrapeDuration: desc("scrape", "duration_seconds", "How long the last refresh from the controller took.", nil),
+
+		deviceUp:       desc("device", "up", "Whether the device is connected to the controller.", deviceLabels),
+		deviceCpu:      desc("device", "cpu_utilization_percent", "Device cpu utilization.", deviceLabels),
+		deviceMem:      desc("device", "memory_utilization_percent", "Device memory utilization.", deviceLabels),
+		deviceUptime:   desc("device", "uptime_seconds", "Device uptime.", deviceLabels),
+		deviceUpload:   desc("device", "upload_bytes_total", "Bytes uploaded by the device.", deviceLabels),
+		deviceDownload: desc("device", "download_bytes_total", "Bytes downloaded by the device.", deviceLabels),
+		deviceClients:  desc("device", "clients", "Clients connected to the device.", deviceLabels),
+		deviceUpgrade:  desc("device", "upgrade_available", "Whether a firmware upgrade is available.", deviceLabels),
+		deviceScrape:   desc("device", "scrape_success", "Whether the ports or wans of the device could be read.", deviceLabels),
+
+		radioClients: desc("radio", "clients", "Clients connected to the radio.", radioLabels),
+		radioTxUtil:  desc("radio", "tx_utilization_percent", "Radio transmit utilization.", radioLabels),
+		radioRxUtil:  desc("radio", "rx_utilization_percent", "Radio receive utilization.", radioLabels),
+		radioInter:   desc("radio", "interference_utilization_percent", "Radio interference utilization.", radioLabels),
+		radioTxPower: desc("radio", "tx_power_dbm", "Radio transmit power.", radioLabels),
+
+		portUp:       desc("switch_port", "up", "Whether the switch port has link.", portLabels),
+		portSpeed:    desc("switch_port", "link_speed", "Switch port link speed setting, see omada.LinkSpeed constants.", portLabels),
+		portPoe:      desc("switch_port", "poe_watts", "Power drawn over poe.", portLabels),
+		portRx:       desc("switch_port", "rx_bytes_total", "Bytes received on the port.", portLabels),
+		portTx:       desc("switch_port", "tx_bytes_total", "Bytes sent on the port.", portLabels),
+		portRxErrors: desc("switch_port", "rx_errors_total", "Receive errors on the port.", portLabels),
+		portTxErrors: desc("switch_port", "tx_errors_total", "Transmit errors on the port.", portLabels),
+
+		clientRssi:     desc("client", "rssi_dbm", "Wireless client signal strength.", clientLabels),
+		clientSnr:      desc("client", "snr_db", "Wireless client signal to noise ratio.", clientLabels),
+		clientDown:     desc("client", "download_bytes_total", "Bytes downloaded by the client.", clientLabels),
+		clientUp:       desc("client", "upload_bytes_total", "Bytes uploaded by the client.", clientLabels),
+		clientUptime:   desc("client", "uptime_seconds", "How long the client has been connected.", clientLabels),
+		clientActivity: desc("client", "activity_bytes_per_second", "Current client throughput.", clientLabels),
+
+		wanUp:      desc("wan", "up", "Whether the wan port has link.", wanLabels),
+		wanOnline:  desc("wan", "internet_up", "Whether online detection reports internet access.", wanLabels),
+		wanActive:  desc("wan", "active", "Whether the wan is carrying traffic in failover or load balance.", wanLabels),
+		wanLatency: desc("wan", "latency_milliseconds", "Latency measured by online detection.", wanLabels),
+		wanLoss:    desc("wan", "loss_ratio", "Packet loss measured by online detection.", wanLabels),
+		wanRx:      desc("wan", "rx_bytes_total", "Bytes received on the wan.", wanLabels),
+		wanTx:      desc("wan", "tx_bytes_total", "Bytes sent on the wan.", wanLabels),
+		wanRxRate:  desc("wan", "rx_rate_bytes_per_second", "Current wan receive rate.", wanLabels),
+		wanTxRate:  desc("wan", "tx_rate_bytes_per_second", "Current wan transmit rate.", wanLabels),
+	}
+}
+
+func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
+
+	descs := []*prometheus.Desc{
+		e.scrapeSuccess, e.scrapeDuration,
+		e.deviceUp, e.deviceCpu, e.deviceMem, e.deviceUptime, e.deviceUpload, e.deviceDownload, e.deviceClients, e.deviceUpgrade, e.deviceScrape,
+		e.radioClients, e.radioTxUtil, e.radioRxUtil, e.radioInter, e.radioTxPower,
+		e.portUp, e.portSpeed, e.portPoe, e.portRx, e.portTx, e.portRxErrors, e.portTxErrors,
+		e.clientRssi, e.clientSnr, e.clientDown, e.clientUp, e.clientUptime, e.clientActivity,
+		e.wanUp, e.wanOnline, e.wanActive, e.wanLatency, e.wanLoss, e.wanRx, e.wanTx, e.wanRxRate, e.wanTxRate,
+	}
+
+	for _, d := range descs {
+		ch <- d
+	}
+
+}
+
+func (e *Collector) Collect(ch chan<- prometheus.Metric) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// on failure the last good metrics are kept and only scrape_success
+	// drops, so a controller blip does not leave gaps in every series
+	if time.Since(e.lastRefresh) >= e.opts.CacheTTL {
+		start := time.Now()
+		metrics, err := e.refresh()
+		if err != nil && e.opts.Relogin != nil && omada.IsAuthError(err) {
+			if err = e.opts.Relogin(); err == nil {
+				metrics, err = e.refresh()
+			}
+		}
+		e.lastRefresh = start
+		e.lastTook = time.Since(start)
+		e.lastErr = err
+		if err == nil {
+			e.metrics = metrics
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(e.scrapeDuration, prometheus.GaugeValue, e.lastTook.Seconds())
+
+	success := 1.0
+	if e.lastErr != nil {
+		success = 0
+	}
+	ch <- prometheus.MustNewConstMetric(e.scrapeSuccess, prometheus.GaugeValue, success)
+
+	for _, m := range e.metrics {
+		ch <- m
+	}
+
+}
+
+func (e *Collector) refresh() ([]prometheus.Metric, error) {
+
+	siteIds := e.opts.SiteIds
+	if len(siteIds) == 0 {
+		siteIds = e.c.SiteIds()
+	}
+
+	var metrics []prometheus.Metric
+	for _, siteId := range siteIds {
+		site, err := e.c.WithSite(siteId)
+		if err != nil {
+			return nil, err
+		}
+		siteMetrics, err := e.collectSite(site, siteId)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, siteMetrics...)
+	}
+
+	return metrics, nil
+
+}
+
+type metricList []prometheus.Metric
+
+func (l *metricList) add(desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labels ...string) {
+	*l = append(*l, prometheus.MustNewConstMetric(desc, valueType, value, labels...))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (e *Collector) collectSite(site *omada.Controller, siteId string) ([]prometheus.Metric, error) {
+
+	clients, devices, err := site.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	var m metricList
+	for _, d := range devices {
+		labels := []string{siteId, d.Name, d.Model, d.Mac, d.Type}
+
+		m.add(e.deviceUp, prometheus.GaugeValue, boolValue(d.StatusCategory == 1), labels...)
+		m.add(e.deviceCpu, prometheus.GaugeValue, float64(d.CPUUtil), labels...)
+		m.add(e.deviceMem, prometheus.GaugeValue, float64(d.MemUtil), labels...)
+		m.add(e.deviceUptime, prometheus.GaugeValue, float64(d.UptimeLong), labels...)
+		m.add(e.deviceUpload, prometheus.CounterValue, float64(d.Upload), labels...)
+		m.add(e.deviceDownload, prometheus.CounterValue, float64(d.Download), labels...)
+		m.add(e.deviceClients, prometheus.GaugeValue, float64(d.ClientNum), labels...)
+		m.add(e.deviceUpgrade, prometheus.GaugeValue, boolValue(d.NeedUpgrade), labels...)
+
+		// a device whose ports or wans cannot be read only loses those
+		// series, unless the session expired and every request will fail
+		var err error
+		switch d.Type {
+		case "ap":
+			e.collectRadios(&m, d, labels)
+		case "switch":
+			err = e.collectPorts(&m, site, d, labels)
+		case "gateway":
+			err = e.collectWans(&m, site, d, labels)
+		}
+		if err != nil && omada.IsAuthError(err) {
+			return nil, err
+		}
+		if err != nil && e.opts.OnError != nil {
+			e.opts.OnError(fmt.Errorf("%s %s: %w", d.Type, d.Mac, err))
+		}
+		m.add(e.deviceScrape, prometheus.GaugeValue, boolValue(err == nil), labels...)
+	}
+
+	for _, c := range clients {
+		labels := []string{siteId, c.DnsName, c.MAC, c.Ssid}
+
+		if c.Wireless {
+			m.add(e.clientRssi, prometheus.GaugeValue, float64(c.Rssi), labels...)
+			m.add(e.clientSnr, prometheus.GaugeValue, float64(c.Snr), labels...)
+		}
+		m.add(e.clientDown, prometheus.CounterValue, float64(c.TrafficDown), labels...)
+		m.add(e.clientUp, prometheus.CounterValue, float64(c.TrafficUp), labels...)
+		m.add(e.clientUptime, prometheus.GaugeValue, float64(c.Uptime), labels...)
+		m.add(e.clientActivity, prometheus.GaugeValue, float64(c.Activity), labels...)
+	}
+
+	return m, nil
+
+}
+
+func (e *Collector) collectRadios(m *metricList, d omada.Device, labels []string) {
+
+	radio := func(band string) []string {
+		return append(append([]string{}, labels...), band)
+	}
+
+	m.add(e.radioClients, prometheus.GaugeValue, float64(d.ClientNum2G), radio("2g")...)
+	m.add(e.radioClients, prometheus.GaugeValue, float64(d.ClientNum5G), radio("5g")...)
+	if d.DeviceMisc.Support5G2 {
+		m.add(e.radioClients, prometheus.GaugeValue, float64(d.ClientNum5G2), radio("5g2")...)
+	}
+	if d.DeviceMisc.Support6G {
+		m.add(e.radioClients, prometheus.GaugeValue, float64(d.ClientNum6G), radio("6g")...)
+	}
+
+	m.add(e.radioTxUtil, prometheus.GaugeValue, float64(d.Wp2G.TxUtil), radio("2g")...)
+	m.add(e.radioRxUtil, prometheus.GaugeValue, float64(d.Wp2G.RxUtil), radio("2g")...)
+	m.add(e.radioInter, prometheus.GaugeValue, float64(d.Wp2G.InterUtil), radio("2g")...)
+	m.add(e.radioTxPower, prometheus.GaugeValue, float64(d.Wp2G.TxPower), radio("2g")...)
+
+	if d.DeviceMisc.Support5G {
+		m.add(e.radioTxUtil, prometheus.GaugeValue, float64(d.Wp5G.TxUtil), radio("5g")...)
+		m.add(e.radioRxUtil, prometheus.GaugeValue, float64(d.Wp5G.RxUtil), radio("5g")...)
+		m.add(e.radioInter, prometheus.GaugeValue, float64(d.Wp5G.InterUtil), radio("5g")...)
+		m.add(e.radioTxPower, prometheus.GaugeValue, float64(d.Wp5G.TxPower), radio("5g")...)
+	}
+
+}
+
+func (e *Collector) collectPorts(m *metricList, site *omada.Controller, d omada.Device, labels []string) error {
+
+	ports, err := site.GetSwitchPorts(d.Mac)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range ports {
+		l := append(append([]string{}, labels...), strconv.Itoa(p.Port), p.Name)
+		m.add(e.portUp, prometheus.GaugeValue, boolValue(p.LinkUp()), l...)
+		m.add(e.portSpeed, prometheus.GaugeValue, float64(p.PortStatus.LinkSpeed), l...)
+		m.add(e.portPoe, prometheus.GaugeValue, p.PortStatus.PoePower, l...)
+		m.add(e.portRx, prometheus.CounterValue, float64(p.PortStatus.Rx), l...)
+		m.add(e.portTx, prometheus.CounterValue, float64(p.PortStatus.Tx), l...)
+		m.add(e.portRxErrors, prometheus.CounterValue, float64(p.PortStatus.RxErrors), l...)
+		m.add(e.portTxErrors, prometheus.CounterValue, float64(p.PortStatus.TxErrors), l...)
+	}
+
+	return nil
+
+}
+
+func (e *Collector) collectWans(m *metricList, site *omada.Controller, d omada.Device, labels []string) error {
+
+	wans, err := site.GetGatewayWanStatusForDevice(d)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range wans {
+		l := append(append([]string{}, labels...), strconv.Itoa(w.Port), w.Name)
+		m.add(e.wanUp, prometheus.GaugeValue, boolValue(w.LinkUp()), l...)
+		m.add(e.wanOnline, prometheus.GaugeValue, boolValue(w.Online()), l...)
+		m.add(e.wanActive, prometheus.GaugeValue, boolValue(w.Active), l...)
+		m.add(e.wanLatency, prometheus.GaugeValue, float64(w.Latency), l...)
+		m.add(e.wanLoss, prometheus.GaugeValue, w.Loss/100, l...)
+		m.add(e.wanRx, prometheus.CounterValue, float64(w.Rx), l...)
+		m.add(e.wanTx, prometheus.CounterValue, float64(w.Tx), l...)
+		m.add(e.wanRxRate, prometheus.GaugeValue, float64(w.RxRate), l...)
+		m.add(e.wanTxRate, prometheus.GaugeValue, float64(w.TxRate), l...)
+	}
+
+	return nil
+
+}
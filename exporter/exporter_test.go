@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	omada "github.com/dougbw/go-omada"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeController answers the requests a refresh makes for a single site
+// holding a switch, a gateway and a client.
+func fakeController(t *testing.T, handle func(w http.ResponseWriter, r *http.Request) bool) *omada.Controller {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle != nil && handle(w, r) {
+			return
+		}
+
+		var result interface{}
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, "/login"):
+			result = map[string]string{"token": "token"}
+		case strings.HasSuffix(path, "/users/current"):
+			result = map[string]interface{}{"privilege": map[string]interface{}{
+				"sites": []omada.Sites{{Name: "Home", Key: "site1"}},
+			}}
+		case strings.HasSuffix(path, "/clients"):
+			result = map[string]interface{}{"data": []omada.Client{{Name: "laptop", Ip: "10.0.0.5", MAC: "BB-00-00-00-00-01"}}}
+		case strings.HasSuffix(path, "/devices"):
+			result = []omada.Device{
+				{Name: "core", Mac: "AA-00-00-00-00-01", Type: "switch", Site: "Home"},
+				{Name: "edge", Mac: "AA-00-00-00-00-02", Type: "gateway", Site: "Home"},
+			}
+		case strings.Contains(path, "/switches/"):
+			result = []omada.SwitchPort{{Port: 1, Name: "Port1"}}
+		case strings.Contains(path, "/gateways/"):
+			result = map[string]interface{}{"portStats": []omada.GatewayWanPort{{Port: 1, Name: "WAN1", Type: 0}}}
+		default:
+			t.Errorf("unexpected request %s", path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	c := omada.New(server.URL)
+	if err := c.Login("user", "pass", "Home"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &c
+
+}
+
+func gather(t *testing.T, collector prometheus.Collector) map[string][]*dto.Metric {
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string][]*dto.Metric)
+	for _, family := range families {
+		metrics[family.GetName()] = family.GetMetric()
+	}
+
+	return metrics
+
+}
+
+func label(m *dto.Metric, name string) string {
+
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+
+	return ""
+
+}
+
+func TestSiteLabel(t *testing.T) {
+
+	metrics := gather(t, NewCollector(fakeController(t, nil), Options{}))
+
+	for _, name := range []string{"omada_device_up", "omada_client_uptime_seconds", "omada_switch_port_up"} {
+		if len(metrics[name]) == 0 {
+			t.Errorf("%s: no metrics", name)
+		}
+		for _, m := range metrics[name] {
+			if got := label(m, "site"); got != "site1" {
+				t.Errorf("%s: got site %q, want site1", name, got)
+			}
+		}
+	}
+
+}
+
+func TestRefreshFetchesDevicesOnce(t *testing.T) {
+
+	var devices int
+	c := fakeController(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.HasSuffix(r.URL.Path, "/devices") {
+			devices++
+		}
+		return false
+	})
+
+	metrics := gather(t, NewCollector(c, Options{}))
+
+	if len(metrics["omada_wan_up"]) != 1 {
+		t.Errorf("got %d wan metrics, want 1", len(metrics["omada_wan_up"]))
+	}
+	if devices != 1 {
+		t.Errorf("devices fetched %d times, want once", devices)
+	}
+
+}
+
+func TestDeviceErrorKeepsRefreshing(t *testing.T) {
+
+	c := fakeController(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.Contains(r.URL.Path, "/switches/") {
+			w.WriteHeader(http.StatusBadGateway)
+			return true
+		}
+		return false
+	})
+
+	var errs []error
+	metrics := gather(t, NewCollector(c, Options{OnError: func(err error) {
+		errs = append(errs, err)
+	}}))
+
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one for the switch", errs)
+	}
+	if got := metrics["omada_scrape_success"]; len(got) != 1 || got[0].GetGauge().GetValue() != 1 {
+		t.Errorf("got scrape success %v, want 1", got)
+	}
+	if len(metrics["omada_wan_up"]) != 1 || len(metrics["omada_client_uptime_seconds"]) != 1 {
+		t.Error("metrics of the other devices and clients are missing")
+	}
+
+	for _, m := range metrics["omada_device_scrape_success"] {
+		want := 1.0
+		if label(m, "type") == "switch" {
+			want = 0
+		}
+		if got := m.GetGauge().GetValue(); got != want {
+			t.Errorf("%s: got device scrape success %v, want %v", label(m, "name"), got, want)
+		}
+	}
+
+}
@@ -0,0 +1,364 @@
+// Package externaldns is an external-dns webhook provider backed by an Omada
+// controller. Clients and devices are exposed as read-only A and AAAA
+// endpoints under their network's domain. When a RecordWriter is set,
+// records external-dns manages are written through it as well.
+package externaldns
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	omada "github.com/dougbw/go-omada"
+	omadadns "github.com/dougbw/go-omada/dns"
+)
+
+const (
+	RecordTypeA    = "A"
+	RecordTypeAAAA = "AAAA"
+)
+
+type Endpoint struct {
+	DnsName          string             `json:"dnsName"`
+	Targets          []string           `json:"targets"`
+	RecordType       string             `json:"recordType"`
+	SetIdentifier    string             `json:"setIdentifier,omitempty"`
+	RecordTtl        int64              `json:"recordTTL,omitempty"`
+	Labels           map[string]string  `json:"labels,omitempty"`
+	ProviderSpecific []ProviderSpecific `json:"providerSpecific,omitempty"`
+}
+
+type ProviderSpecific struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Changes struct {
+	Create    []Endpoint `json:"create,omitempty"`
+	UpdateOld []Endpoint `json:"updateOld,omitempty"`
+	UpdateNew []Endpoint `json:"updateNew,omitempty"`
+	Delete    []Endpoint `json:"delete,omitempty"`
+}
+
+type DomainFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (f DomainFilter) Match(name string) bool {
+
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, domain := range f.Exclude {
+		if matchDomain(domain, name) {
+			return false
+		}
+	}
+
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, domain := range f.Include {
+		if matchDomain(domain, name) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+func matchDomain(domain string, name string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
+
+// RecordWriter stores the records external-dns manages. Updates are sent as
+// a delete of the old endpoint followed by a create of the new one. Check
+// returns the error Create would fail with, it is called for every endpoint
+// before anything is deleted.
+type RecordWriter interface {
+	Endpoints() ([]Endpoint, error)
+	Check(endpoint Endpoint) error
+	Create(endpoint Endpoint) error
+	Delete(endpoint Endpoint) error
+}
+
+type Options struct {
+	// Domains overrides the domain filter, which defaults to the domains of
+	// every network on the controller.
+	Domains []string
+	Exclude []string
+
+	// Domain is used for hosts whose network has no domain set.
+	Domain string
+	Ttl    int64
+
+	Writer RecordWriter
+
+	// Relogin is called when reading from the controller fails because the
+	// session is no longer valid, after which the read is retried once.
+	Relogin func() error
+
+	// OnReadOnly is called with the changes ApplyChanges drops because no
+	// Writer is set.
+	OnReadOnly func(changes Changes)
+}
+
+type Provider struct {
+	c    *omada.Controller
+	opts Options
+
+	// mu serializes requests so a relogin never runs alongside another
+	// request using the controller session
+	mu sync.Mutex
+}
+
+func New(c *omada.Controller, opts Options) *Provider {
+
+	if opts.Ttl <= 0 {
+		opts.Ttl = 300
+	}
+
+	return &Provider{
+		c:    c,
+		opts: opts,
+	}
+}
+
+func (p *Provider) DomainFilter() (DomainFilter, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.opts.Domains) > 0 {
+		return p.domainFilter(nil), nil
+	}
+
+	generator, err := p.load()
+	if err != nil {
+		return DomainFilter{}, err
+	}
+
+	return p.domainFilter(generator), nil
+
+}
+
+func (p *Provider) domainFilter(generator *omadadns.Generator) DomainFilter {
+
+	filter := DomainFilter{Include: p.opts.Domains, Exclude: p.opts.Exclude}
+	if len(filter.Include) > 0 {
+		return filter
+	}
+
+	domains := make(map[string]bool)
+	if p.opts.Domain != "" {
+		domains[strings.TrimSuffix(p.opts.Domain, ".")] = true
+	}
+	for _, network := range generator.Networks() {
+		if network.Domain != "" {
+			domains[strings.TrimSuffix(network.Domain, ".")] = true
+		}
+	}
+	for domain := range domains {
+		filter.Include = append(filter.Include, domain)
+	}
+	sort.Strings(filter.Include)
+
+	return filter
+
+}
+
+// Records returns the client and device endpoints followed by the ones held
+// by the writer. A name is only ever returned from one of the two, names
+// the controller already provides win.
+func (p *Provider) Records() ([]Endpoint, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	generator, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	filter := p.domainFilter(generator)
+
+	taken := make(map[string]bool)
+	var endpoints []Endpoint
+	for _, endpoint := range p.controllerEndpoints(generator) {
+		if !filter.Match(endpoint.DnsName) {
+			continue
+		}
+		taken[endpointKey(endpoint)] = true
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if p.opts.Writer == nil {
+		return endpoints, nil
+	}
+
+	written, err := p.opts.Writer.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range written {
+		if !filter.Match(endpoint.DnsName) || taken[endpointKey(endpoint)] {
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, nil
+
+}
+
+// ApplyChanges passes changes for A and AAAA records to the writer. Changes
+// touching names that come from controller clients or devices are skipped,
+// those can only be changed by renaming the client on the controller.
+// Without a writer the changes are dropped and passed to OnReadOnly.
+func (p *Provider) ApplyChanges(changes Changes) error {
+
+	if len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete) == 0 {
+		return nil
+	}
+	if p.opts.Writer == nil {
+		if p.opts.OnReadOnly != nil {
+			p.opts.OnReadOnly(changes)
+		}
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	generator, err := p.load()
+	if err != nil {
+		return err
+	}
+	readOnly := make(map[string]bool)
+	for _, endpoint := range p.controllerEndpoints(generator) {
+		readOnly[endpointKey(endpoint)] = true
+	}
+
+	writable := func(endpoint Endpoint) bool {
+		return supported(endpoint) && !readOnly[endpointKey(endpoint)]
+	}
+
+	// an update whose new endpoint would be refused keeps its old records
+	var errs []error
+	refused := make(map[string]bool)
+	for _, endpoint := range append(append([]Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if writable(endpoint) {
+			if err := p.opts.Writer.Check(endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("create %s: %w", endpoint.DnsName, err))
+				refused[endpointKey(endpoint)] = true
+			}
+		}
+	}
+
+	for _, endpoint := range changes.Delete {
+		if writable(endpoint) {
+			if err := p.opts.Writer.Delete(endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("delete %s: %w", endpoint.DnsName, err))
+			}
+		}
+	}
+	for _, endpoint := range changes.UpdateOld {
+		if writable(endpoint) && !refused[endpointKey(endpoint)] {
+			if err := p.opts.Writer.Delete(endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("delete %s: %w", endpoint.DnsName, err))
+			}
+		}
+	}
+	for _, endpoint := range append(append([]Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		if writable(endpoint) && !refused[endpointKey(endpoint)] {
+			if err := p.opts.Writer.Create(endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("create %s: %w", endpoint.DnsName, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// AdjustEndpoints drops record types the provider cannot store, and every
+// endpoint when there is no writer, so external-dns does not keep planning
+// changes that never apply.
+func (p *Provider) AdjustEndpoints(endpoints []Endpoint) []Endpoint {
+
+	adjusted := []Endpoint{}
+	if p.opts.Writer == nil {
+		return adjusted
+	}
+	for _, endpoint := range endpoints {
+		if !supported(endpoint) {
+			continue
+		}
+		if endpoint.RecordTtl <= 0 {
+			endpoint.RecordTtl = p.opts.Ttl
+		}
+		adjusted = append(adjusted, endpoint)
+	}
+
+	return adjusted
+
+}
+
+func (p *Provider) controllerEndpoints(generator *omadadns.Generator) []Endpoint {
+
+	byKey := make(map[string]*Endpoint)
+	var keys []string
+	for _, record := range generator.Records() {
+		if record.Domain == "" {
+			continue
+		}
+
+		recordType := RecordTypeA
+		if record.Addr.Is6() {
+			recordType = RecordTypeAAAA
+		}
+
+		endpoint := Endpoint{DnsName: record.Fqdn(), RecordType: recordType}
+		key := endpointKey(endpoint)
+		if _, ok := byKey[key]; !ok {
+			endpoint.RecordTtl = p.opts.Ttl
+			byKey[key] = &endpoint
+			keys = append(keys, key)
+		}
+		byKey[key].Targets = append(byKey[key].Targets, record.Addr.String())
+	}
+
+	var endpoints []Endpoint
+	for _, key := range keys {
+		endpoints = append(endpoints, *byKey[key])
+	}
+
+	return endpoints
+
+}
+
+func (p *Provider) load() (*omadadns.Generator, error) {
+
+	opts := omadadns.Options{Domain: p.opts.Domain}
+
+	generator, err := omadadns.FromAllSites(p.c, opts)
+	if err != nil && p.opts.Relogin != nil && omada.IsAuthError(err) {
+		if err := p.opts.Relogin(); err != nil {
+			return nil, err
+		}
+		generator, err = omadadns.FromAllSites(p.c, opts)
+	}
+
+	return generator, err
+
+}
+
+func supported(endpoint Endpoint) bool {
+	return endpoint.RecordType == RecordTypeA || endpoint.RecordType == RecordTypeAAAA
+}
+
+func endpointKey(endpoint Endpoint) string {
+	return strings.ToLower(strings.TrimSuffix(endpoint.DnsName, ".")) + "/" + endpoint.RecordType
+}
@@ -0,0 +1,42 @@
+package externaldns
+
+import (
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+
+	var dropped []Changes
+	p := New(nil, Options{OnReadOnly: func(changes Changes) {
+		dropped = append(dropped, changes)
+	}})
+
+	endpoints := []Endpoint{{DnsName: "app.home.lan", RecordType: RecordTypeA, Targets: []string{"10.0.0.5"}}}
+	if adjusted := p.AdjustEndpoints(endpoints); adjusted == nil || len(adjusted) != 0 {
+		t.Errorf("got adjusted endpoints %v, want none", adjusted)
+	}
+
+	if err := p.ApplyChanges(Changes{Create: endpoints}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 1 || len(dropped[0].Create) != 1 {
+		t.Errorf("got dropped changes %v", dropped)
+	}
+
+}
+
+func TestAdjustEndpoints(t *testing.T) {
+
+	p := New(nil, Options{Ttl: 60, Writer: NewStaticDnsWriter(nil)})
+
+	adjusted := p.AdjustEndpoints([]Endpoint{
+		{DnsName: "app.home.lan", RecordType: RecordTypeA},
+		{DnsName: "app.home.lan", RecordType: "TXT"},
+		{DnsName: "db.home.lan", RecordType: RecordTypeAAAA, RecordTtl: 10},
+	})
+
+	if len(adjusted) != 2 || adjusted[0].RecordTtl != 60 || adjusted[1].RecordTtl != 10 {
+		t.Errorf("got %v", adjusted)
+	}
+
+}
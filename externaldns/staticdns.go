@@ -0,0 +1,157 @@
+package externaldns
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+// StaticDnsOwner is the description StaticDnsWriter gives the entries it
+// creates. Entries without it are neither returned nor deleted.
+const StaticDnsOwner = "managed by go-omada external-dns"
+
+// StaticDnsWriter stores endpoints as static dns entries on the gateway, one
+// entry per target. There is nowhere to keep TXT ownership records, so
+// external-dns should run with --registry=noop against it.
+type StaticDnsWriter struct {
+	c *omada.Controller
+}
+
+func NewStaticDnsWriter(c *omada.Controller) *StaticDnsWriter {
+	return &StaticDnsWriter{c: c}
+}
+
+// Endpoints returns the enabled entries the writer created, grouped by name
+// and record type.
+func (w *StaticDnsWriter) Endpoints() ([]Endpoint, error) {
+
+	entries, err := w.c.GetStaticDns()
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*Endpoint)
+	var keys []string
+	for _, entry := range entries {
+		addr, err := netip.ParseAddr(entry.Ip)
+		if !entry.Status || entry.Description != StaticDnsOwner || err != nil {
+			continue
+		}
+
+		recordType := RecordTypeA
+		if addr.Unmap().Is6() {
+			recordType = RecordTypeAAAA
+		}
+
+		endpoint := Endpoint{DnsName: strings.ToLower(strings.TrimSuffix(entry.Hostname, ".")), RecordType: recordType}
+		key := endpointKey(endpoint)
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &endpoint
+			keys = append(keys, key)
+		}
+		byKey[key].Targets = append(byKey[key].Targets, addr.Unmap().String())
+	}
+
+	sort.Strings(keys)
+
+	var endpoints []Endpoint
+	for _, key := range keys {
+		endpoints = append(endpoints, *byKey[key])
+	}
+
+	return endpoints, nil
+
+}
+
+// Check refuses names that already have an entry the writer did not
+// create, such as one added by hand or by SyncStaticDns.
+func (w *StaticDnsWriter) Check(endpoint Endpoint) error {
+
+	entries, err := w.c.GetStaticDns()
+	if err != nil {
+		return err
+	}
+
+	return checkOwner(entries, endpoint)
+
+}
+
+// Create refuses the same names as Check.
+func (w *StaticDnsWriter) Create(endpoint Endpoint) error {
+
+	entries, err := w.c.GetStaticDns()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOwner(entries, endpoint); err != nil {
+		return err
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(endpoint.DnsName, "."))
+	for _, target := range endpoint.Targets {
+		entry := omada.StaticDns{
+			Status:      true,
+			Hostname:    name,
+			Ip:          target,
+			Description: StaticDnsOwner,
+		}
+		if _, err := w.c.CreateStaticDns(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// Delete removes the entries the writer created for the endpoint's name whose
+// ip is one of its targets, leaving entries added for other targets or by
+// anything else in place.
+func (w *StaticDnsWriter) Delete(endpoint Endpoint) error {
+
+	entries, err := w.c.GetStaticDns()
+	if err != nil {
+		return err
+	}
+
+	targets := make(map[string]bool)
+	for _, target := range endpoint.Targets {
+		if addr, err := netip.ParseAddr(target); err == nil {
+			targets[addr.Unmap().String()] = true
+		}
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(endpoint.DnsName, "."))
+	for _, entry := range entries {
+		addr, err := netip.ParseAddr(entry.Ip)
+		if err != nil || entry.Description != StaticDnsOwner || !targets[addr.Unmap().String()] {
+			continue
+		}
+		if strings.ToLower(strings.TrimSuffix(entry.Hostname, ".")) != name {
+			continue
+		}
+		if err := w.c.DeleteStaticDns(entry.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func checkOwner(entries []omada.StaticDns, endpoint Endpoint) error {
+
+	name := strings.ToLower(strings.TrimSuffix(endpoint.DnsName, "."))
+	for _, entry := range entries {
+		if entry.Description != StaticDnsOwner && strings.ToLower(strings.TrimSuffix(entry.Hostname, ".")) == name {
+			return fmt.Errorf("static dns %s exists and is not managed by external-dns", name)
+		}
+	}
+
+	return nil
+
+}
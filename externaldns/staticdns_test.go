@@ -0,0 +1,105 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+func TestStaticDnsWriterOwnership(t *testing.T) {
+
+	entries := []omada.StaticDns{
+		{Id: "1", Status: true, Hostname: "app.home.lan", Ip: "10.0.0.5", Description: StaticDnsOwner},
+		{Id: "2", Status: true, Hostname: "app.home.lan", Ip: "10.0.0.6", Description: StaticDnsOwner},
+		{Id: "3", Status: true, Hostname: "nas.home.lan", Ip: "10.0.0.7", Description: omada.StaticDnsSyncOwner},
+		{Id: "4", Status: true, Hostname: "manual.home.lan", Ip: "10.0.0.8"},
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		if r.Method == "GET" {
+			result = map[string]interface{}{"data": entries}
+		} else {
+			requests = append(requests, r.Method+" "+r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			result = map[string]string{"id": "new"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := omada.New(server.URL)
+	writer := NewStaticDnsWriter(&c)
+
+	endpoints, err := writer.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 || endpoints[0].DnsName != "app.home.lan" || len(endpoints[0].Targets) != 2 {
+		t.Errorf("got endpoints %v", endpoints)
+	}
+
+	for _, name := range []string{"nas.home.lan", "manual.home.lan"} {
+		if err := writer.Create(Endpoint{DnsName: name, RecordType: RecordTypeA, Targets: []string{"10.0.0.9"}}); err == nil {
+			t.Errorf("%s: expected an error for an entry the writer does not own", name)
+		}
+		if err := writer.Delete(Endpoint{DnsName: name, RecordType: RecordTypeA, Targets: []string{"10.0.0.7", "10.0.0.8"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Delete(Endpoint{DnsName: "app.home.lan", RecordType: RecordTypeA, Targets: []string{"10.0.0.6"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Create(Endpoint{DnsName: "web.home.lan.", RecordType: RecordTypeA, Targets: []string{"10.0.0.10"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(requests, ", "); got != "DELETE 2, POST static" {
+		t.Errorf("got requests %s", got)
+	}
+
+}
+
+func TestApplyChangesKeepsRecordsOnConflict(t *testing.T) {
+
+	entries := []omada.StaticDns{
+		{Id: "1", Status: true, Hostname: "app.home.lan", Ip: "10.0.0.5", Description: StaticDnsOwner},
+		{Id: "2", Status: true, Hostname: "app.home.lan", Ip: "10.0.0.6"},
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		if r.Method == "GET" {
+			result = map[string]interface{}{"data": entries}
+		} else {
+			requests = append(requests, r.Method+" "+r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			result = map[string]string{"id": "new"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := omada.New(server.URL)
+	p := New(&c, Options{Writer: NewStaticDnsWriter(&c)})
+
+	err := p.ApplyChanges(Changes{
+		UpdateOld: []Endpoint{{DnsName: "app.home.lan", RecordType: RecordTypeA, Targets: []string{"10.0.0.5"}}},
+		UpdateNew: []Endpoint{{DnsName: "app.home.lan", RecordType: RecordTypeA, Targets: []string{"10.0.0.7"}}},
+		Create:    []Endpoint{{DnsName: "web.home.lan", RecordType: RecordTypeA, Targets: []string{"10.0.0.10"}}},
+	})
+	if err == nil {
+		t.Error("expected an error for the entry the writer does not own")
+	}
+
+	// the update is refused before its old entry is deleted, the create
+	// still goes ahead
+	if got := strings.Join(requests, ", "); got != "POST static" {
+		t.Errorf("got requests %s", got)
+	}
+
+}
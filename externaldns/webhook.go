@@ -0,0 +1,107 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// MediaType is the content type the external-dns webhook protocol
+// negotiates on.
+const MediaType = "application/external.dns.webhook+json;version=1"
+
+// Handler serves the external-dns webhook api:
+//
+//	GET  /                 domain filter negotiation
+//	GET  /records          current endpoints
+//	POST /records          apply changes
+//	POST /adjustendpoints  drop endpoints the provider cannot store
+//	GET  /healthz          health check
+func (p *Provider) Handler() http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.serveNegotiate)
+	mux.HandleFunc("/records", p.serveRecords)
+	mux.HandleFunc("/adjustendpoints", p.serveAdjustEndpoints)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+
+}
+
+func (p *Provider) serveNegotiate(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := p.DomainFilter()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, filter)
+
+}
+
+func (p *Provider) serveRecords(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		endpoints, err := p.Records()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if endpoints == nil {
+			endpoints = []Endpoint{}
+		}
+		writeJSON(w, endpoints)
+
+	case "POST":
+		var changes Changes
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := p.ApplyChanges(changes); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+
+}
+
+func (p *Provider) serveAdjustEndpoints(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var endpoints []Endpoint
+	if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, p.AdjustEndpoints(endpoints))
+
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", MediaType)
+	json.NewEncoder(w).Encode(v)
+}
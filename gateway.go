@@ -0,0 +1,290 @@
+package omada
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+const (
+	WanConnectionDhcp   = "dhcp"
+	WanConnectionStatic = "static"
+	WanConnectionPppoe  = "pppoe"
+)
+
+const (
+	WanModeFailover    = 0
+	WanModeLoadBalance = 1
+)
+
+const (
+	gatewayPortTypeWan = 0
+)
+
+type gatewayDetail struct {
+	Mac       string           `json:"mac"`
+	Name      string           `json:"name"`
+	PortStats []GatewayWanPort `json:"portStats"`
+}
+
+type GatewayWanPort struct {
+	Port          int     `json:"port"`
+	Name          string  `json:"name"`
+	Type          int     `json:"type"`
+	Mode          int     `json:"mode"`
+	Status        int     `json:"status"`
+	InternetState int     `json:"internetState"`
+	Active        bool    `json:"active"`
+	Proto         string  `json:"proto"`
+	Ip            string  `json:"ip"`
+	PublicIp      string  `json:"publicIp"`
+	Gateway       string  `json:"gateway"`
+	PrimaryDns    string  `json:"primaryDns"`
+	SecondaryDns  string  `json:"secondaryDns"`
+	Speed         int     `json:"speed"`
+	Duplex        int     `json:"duplex"`
+	Latency       int     `json:"latency"`
+	Loss          float64 `json:"loss"`
+	Rx            int64   `json:"rx"`
+	Tx            int64   `json:"tx"`
+	RxRate        int64   `json:"rxRate"`
+	TxRate        int64   `json:"txRate"`
+	Uptime        string  `json:"uptime"`
+	UptimeLong    int64   `json:"uptimeLong"`
+}
+
+func (p GatewayWanPort) LinkUp() bool {
+	return p.Status == 1
+}
+
+func (p GatewayWanPort) Online() bool {
+	return p.InternetState == 1
+}
+
+type WanSettings struct {
+	Mode  int               `json:"wanMode"`
+	Ports []WanPortSettings `json:"wanPortSettings"`
+}
+
+type WanPortSettings struct {
+	PortUuid       string `json:"portUuid"`
+	PortName       string `json:"portName"`
+	Port           int    `json:"port"`
+	ConnectionType string `json:"proto"`
+	Ip             string `json:"ip,omitempty"`
+	Netmask        string `json:"netmask,omitempty"`
+	Gateway        string `json:"gateway,omitempty"`
+	PrimaryDns     string `json:"primaryDns,omitempty"`
+	SecondaryDns   string `json:"secondaryDns,omitempty"`
+	PppoeUsername  string `json:"pppoeUsername,omitempty"`
+	PppoePassword  string `json:"pppoePassword,omitempty"`
+	VlanEnable     bool   `json:"vlanEnable"`
+	VlanId         int    `json:"vlanId,omitempty"`
+	Mtu            int    `json:"mtu"`
+}
+
+func (c *Controller) GetGatewayWanStatus(mac string) ([]GatewayWanPort, error) {
+
+	if err := c.checkGateway(mac); err != nil {
+		return nil, err
+	}
+
+	return c.getGatewayWanStatus(mac)
+
+}
+
+// GetGatewayWanStatusForDevice is GetGatewayWanStatus for a device that has
+// already been fetched, it skips looking the device up again.
+func (c *Controller) GetGatewayWanStatusForDevice(device Device) ([]GatewayWanPort, error) {
+
+	if device.Type != "gateway" {
+		return nil, fmt.Errorf("device is not a gateway: %s", device.Mac)
+	}
+
+	return c.getGatewayWanStatus(device.Mac)
+
+}
+
+func (c *Controller) getGatewayWanStatus(mac string) ([]GatewayWanPort, error) {
+
+	url := c.siteURL(c.siteId, "gateways/"+mac)
+
+	var detail gatewayDetail
+	if err := c.doRequest("GET", url, nil, &detail); err != nil {
+		return nil, err
+	}
+
+	var wans []GatewayWanPort
+	for _, port := range detail.PortStats {
+		if port.Type != gatewayPortTypeWan {
+			continue
+		}
+		wans = append(wans, port)
+	}
+
+	sort.Slice(wans, func(i, j int) bool {
+		return wans[i].Port < wans[j].Port
+	})
+
+	return wans, nil
+
+}
+
+func (c *Controller) GetActiveWan(mac string) ([]GatewayWanPort, error) {
+
+	wans, err := c.GetGatewayWanStatus(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []GatewayWanPort
+	for _, wan := range wans {
+		if wan.Active {
+			active = append(active, wan)
+		}
+	}
+
+	return active, nil
+
+}
+
+func (c *Controller) GetWanSettings() (WanSettings, error) {
+
+	url := c.siteURL(c.siteId, "setting/wan/networks")
+
+	var settings WanSettings
+	if err := c.doRequest("GET", url, nil, &settings); err != nil {
+		return WanSettings{}, err
+	}
+
+	sort.Slice(settings.Ports, func(i, j int) bool {
+		return settings.Ports[i].Port < settings.Ports[j].Port
+	})
+
+	return settings, nil
+
+}
+
+func (c *Controller) UpdateWanPortSettings(settings WanPortSettings) error {
+
+	if settings.PortUuid == "" {
+		return fmt.Errorf("wan port uuid is required")
+	}
+
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/wan/networks/"+settings.PortUuid)
+	return c.doRequest("PATCH", url, settings, nil)
+
+}
+
+func (s WanPortSettings) Validate() error {
+
+	switch s.ConnectionType {
+	case WanConnectionDhcp:
+	case WanConnectionStatic:
+		if err := validateStaticWan(s); err != nil {
+			return err
+		}
+	case WanConnectionPppoe:
+		if s.PppoeUsername == "" {
+			return fmt.Errorf("wan %s: pppoe username is required", s.PortName)
+		}
+	default:
+		return fmt.Errorf("wan %s: unknown connection type: %s", s.PortName, s.ConnectionType)
+	}
+
+	if s.VlanEnable && (s.VlanId < 1 || s.VlanId > 4094) {
+		return fmt.Errorf("wan %s: vlan id must be between 1 and 4094, got: %d", s.PortName, s.VlanId)
+	}
+
+	maxMtu := 1500
+	if s.ConnectionType == WanConnectionPppoe {
+		maxMtu = 1492
+	}
+	if s.Mtu != 0 && (s.Mtu < 576 || s.Mtu > maxMtu) {
+		return fmt.Errorf("wan %s: mtu must be between 576 and %d, got: %d", s.PortName, maxMtu, s.Mtu)
+	}
+
+	for _, dns := range []string{s.PrimaryDns, s.SecondaryDns} {
+		if dns == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(dns); err != nil {
+			return fmt.Errorf("wan %s: invalid dns server: %s", s.PortName, dns)
+		}
+	}
+
+	return nil
+
+}
+
+func validateStaticWan(s WanPortSettings) error {
+
+	ip, err := netip.ParseAddr(s.Ip)
+	if err != nil || !ip.Is4() {
+		return fmt.Errorf("wan %s: invalid ip address: %s", s.PortName, s.Ip)
+	}
+
+	mask, err := netip.ParseAddr(s.Netmask)
+	if err != nil || !mask.Is4() {
+		return fmt.Errorf("wan %s: invalid netmask: %s", s.PortName, s.Netmask)
+	}
+	bits := netmaskBits(mask)
+	if bits < 0 {
+		return fmt.Errorf("wan %s: invalid netmask: %s", s.PortName, s.Netmask)
+	}
+
+	gateway, err := netip.ParseAddr(s.Gateway)
+	if err != nil || !gateway.Is4() {
+		return fmt.Errorf("wan %s: invalid gateway: %s", s.PortName, s.Gateway)
+	}
+
+	prefix := netip.PrefixFrom(ip, bits).Masked()
+	if !prefix.Contains(gateway) {
+		return fmt.Errorf("wan %s: gateway %s is not within %s", s.PortName, gateway, prefix)
+	}
+	if ip == gateway {
+		return fmt.Errorf("wan %s: ip address and gateway are the same: %s", s.PortName, ip)
+	}
+
+	return nil
+
+}
+
+// netmaskBits returns the prefix length of a dotted netmask, or -1 if the
+// mask is not contiguous.
+func netmaskBits(mask netip.Addr) int {
+
+	b := mask.As4()
+	n := uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+
+	bits := 0
+	for n&0x80000000 != 0 {
+		bits++
+		n <<= 1
+	}
+	if n != 0 {
+		return -1
+	}
+
+	return bits
+
+}
+
+func (c *Controller) checkGateway(mac string) error {
+
+	device, err := c.findDevice(mac)
+	if err != nil {
+		return err
+	}
+
+	if device.Type != "gateway" {
+		return fmt.Errorf("device is not a gateway: %s", mac)
+	}
+
+	return nil
+
+}
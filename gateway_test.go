@@ -0,0 +1,36 @@
+package omada
+
+import (
+	"testing"
+)
+
+func TestValidateStaticWan(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		ip      string
+		netmask string
+		gateway string
+		valid   bool
+	}{
+		{"valid", "203.0.113.10", "255.255.255.0", "203.0.113.1", true},
+		{"point to point", "203.0.113.10", "255.255.255.254", "203.0.113.11", true},
+		{"ipv6 ip", "2001:db8::10", "255.255.255.0", "203.0.113.1", false},
+		{"ipv6 gateway", "203.0.113.10", "255.255.255.0", "2001:db8::1", false},
+		{"ipv6 ip and gateway", "2001:db8::10", "255.255.255.0", "2001:db8::1", false},
+		{"mapped ipv4", "::ffff:203.0.113.10", "255.255.255.0", "203.0.113.1", false},
+		{"ipv6 netmask", "203.0.113.10", "ffff:ffff::", "203.0.113.1", false},
+		{"non contiguous netmask", "203.0.113.10", "255.0.255.0", "203.0.113.1", false},
+		{"gateway outside subnet", "203.0.113.10", "255.255.255.0", "198.51.100.1", false},
+		{"gateway is the ip", "203.0.113.10", "255.255.255.0", "203.0.113.10", false},
+		{"missing ip", "", "255.255.255.0", "203.0.113.1", false},
+	}
+
+	for _, test := range tests {
+		err := validateStaticWan(WanPortSettings{PortName: "wan1", Ip: test.ip, Netmask: test.netmask, Gateway: test.gateway})
+		if (err == nil) != test.valid {
+			t.Errorf("%s: got error %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+
+}
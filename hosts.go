@@ -0,0 +1,67 @@
+package omada
+
+import (
+	"sort"
+)
+
+// GetHosts returns the clients and devices of the current site. Clients and
+// devices share one namespace, so a client and a device never get the same
+// DnsName. Use it over GetClients and GetDevices when both are needed, each
+// of those has to fetch both lists to name them.
+func (c *Controller) GetHosts() ([]Client, []Device, error) {
+	return c.getSiteHosts(c.siteId)
+}
+
+// GetAllHosts is GetHosts across every site. Names are unique within each
+// site, or across all sites when the Namer has AcrossSites set.
+func (c *Controller) GetAllHosts() ([]Client, []Device, error) {
+
+	var clients []Client
+	var devices []Device
+	for _, v := range c.allSiteIds {
+		siteClients, siteDevices, err := c.getSiteHosts(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		clients = append(clients, siteClients...)
+		devices = append(devices, siteDevices...)
+	}
+
+	if c.namer.AcrossSites {
+		c.namer.nameHosts(clients, devices)
+	}
+	sortHosts(clients, devices)
+
+	return clients, devices, nil
+
+}
+
+func (c *Controller) getSiteHosts(siteId string) ([]Client, []Device, error) {
+
+	clients, err := c.getSiteClients(siteId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	devices, err := c.getSiteDevices(siteId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.namer.nameHosts(clients, devices)
+	sortHosts(clients, devices)
+
+	return clients, devices, nil
+
+}
+
+func sortHosts(clients []Client, devices []Device) {
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].DnsName < clients[j].DnsName
+	})
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+
+}
@@ -0,0 +1,349 @@
+package omada
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	IpGroupTypeIp     = 0
+	IpGroupTypeIpPort = 1
+)
+
+type PortRange struct {
+	Start uint16
+	End   uint16
+}
+
+func ParsePortRange(value string) (PortRange, error) {
+
+	start, end, found := strings.Cut(value, "-")
+	if !found {
+		end = start
+	}
+
+	first, err := strconv.ParseUint(strings.TrimSpace(start), 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port: %s", value)
+	}
+	last, err := strconv.ParseUint(strings.TrimSpace(end), 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port: %s", value)
+	}
+
+	if first < 1 || last < first {
+		return PortRange{}, fmt.Errorf("invalid port range: %s", value)
+	}
+
+	return PortRange{Start: uint16(first), End: uint16(last)}, nil
+
+}
+
+func (r PortRange) Size() int {
+	return int(r.End) - int(r.Start) + 1
+}
+
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(int(r.Start))
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+type IpGroup struct {
+	Id         string
+	Name       string
+	Type       int
+	Prefixes   []netip.Prefix
+	PortRanges []PortRange
+}
+
+type ipGroupEntry struct {
+	Ip          string `json:"ip"`
+	Mask        int    `json:"mask"`
+	Description string `json:"description,omitempty"`
+}
+
+type ipGroupWire struct {
+	GroupId  string         `json:"groupId,omitempty"`
+	Name     string         `json:"name"`
+	Type     int            `json:"type"`
+	IpList   []ipGroupEntry `json:"ipList"`
+	PortList []string       `json:"portList,omitempty"`
+}
+
+func (g IpGroup) MarshalJSON() ([]byte, error) {
+
+	wire := ipGroupWire{
+		GroupId: g.Id,
+		Name:    g.Name,
+		Type:    g.Type,
+		IpList:  []ipGroupEntry{},
+	}
+
+	for _, prefix := range g.Prefixes {
+		prefix = prefix.Masked()
+		wire.IpList = append(wire.IpList, ipGroupEntry{Ip: prefix.Addr().String(), Mask: prefix.Bits()})
+	}
+	for _, ports := range g.PortRanges {
+		wire.PortList = append(wire.PortList, ports.String())
+	}
+
+	return json.Marshal(wire)
+
+}
+
+func (g *IpGroup) UnmarshalJSON(data []byte) error {
+
+	var wire ipGroupWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	group := IpGroup{
+		Id:   wire.GroupId,
+		Name: wire.Name,
+		Type: wire.Type,
+	}
+
+	for _, entry := range wire.IpList {
+		addr, err := netip.ParseAddr(entry.Ip)
+		if err != nil {
+			return fmt.Errorf("ip group %s: invalid ip: %s", wire.Name, entry.Ip)
+		}
+		prefix, err := addr.Prefix(entry.Mask)
+		if err != nil {
+			return fmt.Errorf("ip group %s: invalid mask: %s/%d", wire.Name, entry.Ip, entry.Mask)
+		}
+		group.Prefixes = append(group.Prefixes, prefix)
+	}
+
+	for _, value := range wire.PortList {
+		ports, err := ParsePortRange(value)
+		if err != nil {
+			return fmt.Errorf("ip group %s: %w", wire.Name, err)
+		}
+		group.PortRanges = append(group.PortRanges, ports)
+	}
+
+	*g = group
+	return nil
+
+}
+
+func (g IpGroup) Validate() error {
+
+	if g.Name == "" {
+		return fmt.Errorf("ip group name is required")
+	}
+
+	switch g.Type {
+	case IpGroupTypeIp:
+		if len(g.PortRanges) > 0 {
+			return fmt.Errorf("ip group %s: port ranges are only valid for ip-port groups", g.Name)
+		}
+	case IpGroupTypeIpPort:
+		if len(g.PortRanges) == 0 {
+			return fmt.Errorf("ip group %s: ip-port groups need at least one port range", g.Name)
+		}
+	default:
+		return fmt.Errorf("ip group %s: unknown type: %d", g.Name, g.Type)
+	}
+
+	if len(g.Prefixes) == 0 {
+		return fmt.Errorf("ip group %s: at least one prefix is required", g.Name)
+	}
+
+	for _, prefix := range g.Prefixes {
+		if !prefix.IsValid() {
+			return fmt.Errorf("ip group %s: invalid prefix", g.Name)
+		}
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetIpGroups() ([]IpGroup, error) {
+
+	var groups []IpGroup
+	if err := c.getPaged(c.siteId, "setting/profiles/groups", &groups); err != nil {
+		return nil, err
+	}
+
+	var ipGroups []IpGroup
+	for _, group := range groups {
+		if group.Type == IpGroupTypeIp || group.Type == IpGroupTypeIpPort {
+			ipGroups = append(ipGroups, group)
+		}
+	}
+
+	sort.Slice(ipGroups, func(i, j int) bool {
+		return ipGroups[i].Name < ipGroups[j].Name
+	})
+
+	return ipGroups, nil
+
+}
+
+func (c *Controller) GetIpGroup(id string) (IpGroup, error) {
+
+	groups, err := c.GetIpGroups()
+	if err != nil {
+		return IpGroup{}, err
+	}
+
+	for _, group := range groups {
+		if group.Id == id {
+			return group, nil
+		}
+	}
+
+	return IpGroup{}, fmt.Errorf("ip group not found: %s", id)
+
+}
+
+func (c *Controller) CreateIpGroup(group IpGroup) (IpGroup, error) {
+
+	group.Id = ""
+	if err := group.Validate(); err != nil {
+		return IpGroup{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/profiles/groups")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, group, &created); err != nil {
+		return IpGroup{}, err
+	}
+
+	group.Id = created.Id
+	return group, nil
+
+}
+
+func (c *Controller) UpdateIpGroup(group IpGroup) error {
+
+	if group.Id == "" {
+		return fmt.Errorf("ip group id is required")
+	}
+
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("setting/profiles/groups/%d/%s", group.Type, group.Id))
+	return c.doRequest("PATCH", url, group, nil)
+
+}
+
+func (c *Controller) DeleteIpGroup(id string) error {
+
+	group, err := c.GetIpGroup(id)
+	if err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("setting/profiles/groups/%d/%s", group.Type, group.Id))
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+// IpGroupFromClients builds an ip group holding the current address of each
+// client mac. Macs that are not currently connected are returned as missing.
+// Each address is added once, however many of the macs resolve to it.
+func (c *Controller) IpGroupFromClients(name string, macs []string) (IpGroup, []string, error) {
+
+	clients, err := c.GetClients()
+	if err != nil {
+		return IpGroup{}, nil, err
+	}
+
+	addrs := make(map[string]netip.Addr)
+	for _, client := range clients {
+		addr, err := client.Addr()
+		if err != nil {
+			continue
+		}
+		addrs[normalizeMac(client.MAC)] = addr
+	}
+
+	group := IpGroup{Name: name, Type: IpGroupTypeIp}
+	seen := make(map[netip.Addr]bool)
+	var missing []string
+	for _, mac := range macs {
+		addr, ok := addrs[normalizeMac(mac)]
+		if !ok {
+			missing = append(missing, mac)
+			continue
+		}
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		group.Prefixes = append(group.Prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	sort.Slice(group.Prefixes, func(i, j int) bool {
+		return group.Prefixes[i].Addr().Less(group.Prefixes[j].Addr())
+	})
+
+	return group, missing, nil
+
+}
+
+// SyncIpGroupFromClients replaces the prefixes of an existing ip group with
+// the current addresses of the given clients, only writing when they differ.
+func (c *Controller) SyncIpGroupFromClients(id string, macs []string) (bool, []string, error) {
+
+	existing, err := c.GetIpGroup(id)
+	if err != nil {
+		return false, nil, err
+	}
+
+	desired, missing, err := c.IpGroupFromClients(existing.Name, macs)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if len(desired.Prefixes) == 0 {
+		return false, missing, fmt.Errorf("ip group %s: none of the clients are connected", existing.Name)
+	}
+
+	if samePrefixes(existing.Prefixes, desired.Prefixes) {
+		return false, missing, nil
+	}
+
+	existing.Prefixes = desired.Prefixes
+	if err := c.UpdateIpGroup(existing); err != nil {
+		return false, missing, err
+	}
+
+	return true, missing, nil
+
+}
+
+func samePrefixes(a []netip.Prefix, b []netip.Prefix) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := make(map[netip.Prefix]int)
+	for _, prefix := range a {
+		seen[prefix.Masked()]++
+	}
+	for _, prefix := range b {
+		seen[prefix.Masked()]--
+		if seen[prefix.Masked()] < 0 {
+			return false
+		}
+	}
+
+	return true
+
+}
@@ -0,0 +1,76 @@
+// Package listener receives logs pushed by an Omada controller, either as
+// syslog or as webhooks, and turns them into omada.LogEntry values so they
+// can be handled the same way as entries read from the events api.
+package listener
+
+import (
+	"errors"
+	"sync"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+var errDropped = errors.New("event buffer full, entry dropped")
+
+type Options struct {
+	Secret string
+	Buffer int
+
+	// Syslog over udp and tcp and webhooks are received concurrently, but
+	// OnEvent and OnError are never called concurrently with themselves or
+	// each other.
+	OnEvent func(omada.LogEntry)
+	OnError func(err error)
+}
+
+type Listener struct {
+	opts   Options
+	events chan omada.LogEntry
+
+	// callbackMu serializes the OnEvent and OnError callbacks
+	callbackMu sync.Mutex
+}
+
+func New(opts Options) *Listener {
+
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+
+	return &Listener{
+		opts:   opts,
+		events: make(chan omada.LogEntry, opts.Buffer),
+	}
+}
+
+// Events returns the channel entries are sent on when no OnEvent callback is
+// set. When the channel is full new entries are dropped and reported through
+// OnError rather than blocking the receiver.
+func (l *Listener) Events() <-chan omada.LogEntry {
+	return l.events
+}
+
+func (l *Listener) emit(entry omada.LogEntry) {
+
+	if l.opts.OnEvent != nil {
+		l.callbackMu.Lock()
+		defer l.callbackMu.Unlock()
+		l.opts.OnEvent(entry)
+		return
+	}
+
+	select {
+	case l.events <- entry:
+	default:
+		l.error(errDropped)
+	}
+
+}
+
+func (l *Listener) error(err error) {
+	if l.opts.OnError != nil {
+		l.callbackMu.Lock()
+		defer l.callbackMu.Unlock()
+		l.opts.OnError(err)
+	}
+}
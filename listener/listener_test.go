@@ -0,0 +1,53 @@
+package listener
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+func TestCallbacksAreSerialized(t *testing.T) {
+
+	var active int32
+	var calls int32
+	enter := func() {
+		if atomic.AddInt32(&active, 1) != 1 {
+			t.Error("callbacks called concurrently")
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+		atomic.AddInt32(&active, -1)
+	}
+
+	l := New(Options{
+		Secret:  "s3cret",
+		OnEvent: func(omada.LogEntry) { enter() },
+		OnError: func(error) { enter() },
+	})
+	handler := l.WebhookHandler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		secret := "s3cret"
+		if i%4 == 0 {
+			secret = "wrong"
+		}
+		wg.Add(1)
+		go func(secret string) {
+			defer wg.Done()
+			body := `{"Site":"Home","text":["connected"],"timestamp":1709288430000,"shardSecret":"` + secret + `"}`
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		}(secret)
+	}
+	wg.Wait()
+
+	if calls != 20 {
+		t.Errorf("got %d callbacks, want 20", calls)
+	}
+
+}
@@ -0,0 +1,316 @@
+package listener
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+var (
+	macPattern      = `[0-9A-Fa-f]{2}(?:[-:][0-9A-Fa-f]{2}){5}`
+	labeledMacRegex = regexp.MustCompile(`(?i)\b(client|ap|eap|switch|gateway|device)\b[^0-9A-Fa-f]{0,3}(` + macPattern + `)`)
+)
+
+// ParseSyslog parses a single RFC 3164 or RFC 5424 message.
+func ParseSyslog(message []byte) (omada.LogEntry, error) {
+
+	msg := strings.TrimRight(string(message), "\r\n\x00")
+	if !strings.HasPrefix(msg, "<") {
+		return omada.LogEntry{}, fmt.Errorf("syslog message has no priority: %q", msg)
+	}
+
+	end := strings.IndexByte(msg, '>')
+	if end < 2 || end > 4 {
+		return omada.LogEntry{}, fmt.Errorf("syslog message has an invalid priority: %q", msg)
+	}
+	priority, err := strconv.Atoi(msg[1:end])
+	if err != nil || priority > 191 {
+		return omada.LogEntry{}, fmt.Errorf("syslog message has an invalid priority: %q", msg)
+	}
+	msg = msg[end+1:]
+
+	entry := omada.LogEntry{
+		Kind:  omada.LogKindSyslog,
+		Level: syslogLevel(priority % 8),
+	}
+
+	if strings.HasPrefix(msg, "1 ") {
+		err = parseRFC5424(msg[2:], &entry)
+	} else {
+		err = parseRFC3164(msg, &entry)
+	}
+	if err != nil {
+		return omada.LogEntry{}, err
+	}
+
+	linkMacs(&entry)
+	return entry, nil
+
+}
+
+func syslogLevel(severity int) string {
+	switch {
+	case severity <= 3:
+		return omada.LogLevelError
+	case severity == 4:
+		return omada.LogLevelWarning
+	default:
+		return omada.LogLevelInfo
+	}
+}
+
+func parseRFC5424(msg string, entry *omada.LogEntry) error {
+
+	// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fields := strings.SplitN(msg, " ", 6)
+	if len(fields) < 6 {
+		return fmt.Errorf("rfc5424 message is truncated: %q", msg)
+	}
+
+	if fields[0] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("rfc5424 message has an invalid timestamp: %s", fields[0])
+		}
+		entry.Time = t
+	} else {
+		entry.Time = time.Now()
+	}
+
+	entry.Host = nilValue(fields[1])
+	entry.Module = nilValue(fields[2])
+	entry.Key = nilValue(fields[4])
+
+	rest := fields[5]
+	if strings.HasPrefix(rest, "-") {
+		rest = strings.TrimPrefix(rest, "-")
+	} else if strings.HasPrefix(rest, "[") {
+		rest = skipStructuredData(rest)
+	}
+	entry.Content = strings.TrimPrefix(strings.TrimSpace(rest), "\ufeff")
+
+	return nil
+
+}
+
+func nilValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
+
+func skipStructuredData(msg string) string {
+
+	inQuotes := false
+	depth := 0
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case '[':
+			if !inQuotes {
+				depth++
+			}
+		case ']':
+			if !inQuotes {
+				depth--
+				if depth == 0 && (i+1 == len(msg) || msg[i+1] != '[') {
+					return msg[i+1:]
+				}
+			}
+		}
+	}
+
+	return ""
+
+}
+
+func parseRFC3164(msg string, entry *omada.LogEntry) error {
+
+	// Mmm dd hh:mm:ss HOSTNAME TAG: MSG
+	if len(msg) < 16 {
+		return fmt.Errorf("rfc3164 message is truncated: %q", msg)
+	}
+
+	t, err := time.ParseInLocation(time.Stamp, msg[:15], time.Local)
+	if err != nil {
+		return fmt.Errorf("rfc3164 message has an invalid timestamp: %s", msg[:15])
+	}
+	now := time.Now()
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		// messages from late december received in january
+		t = t.AddDate(-1, 0, 0)
+	}
+	entry.Time = t
+
+	rest := strings.TrimSpace(msg[15:])
+	host, rest, _ := strings.Cut(rest, " ")
+	entry.Host = host
+
+	if tag, content, found := strings.Cut(rest, ": "); found && !strings.Contains(tag, " ") {
+		if i := strings.IndexByte(tag, '['); i >= 0 {
+			tag = tag[:i]
+		}
+		entry.Module = tag
+		rest = content
+	}
+	entry.Content = strings.TrimSpace(rest)
+
+	return nil
+
+}
+
+// linkMacs fills in the device and client macs from the message text so the
+// entry can be matched against GetDevices and GetClients.
+func linkMacs(entry *omada.LogEntry) {
+
+	for _, match := range labeledMacRegex.FindAllStringSubmatch(entry.Content, -1) {
+		mac := strings.ToUpper(strings.ReplaceAll(match[2], ":", "-"))
+		switch strings.ToLower(match[1]) {
+		case "client":
+			if entry.ClientMac == "" {
+				entry.ClientMac = mac
+			}
+		default:
+			if entry.DeviceMac == "" {
+				entry.DeviceMac = mac
+			}
+		}
+	}
+
+}
+
+// ServeSyslogUDP reads one message per datagram until ctx is cancelled.
+func (l *Listener) ServeSyslogUDP(ctx context.Context, conn net.PacketConn) error {
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		entry, err := ParseSyslog(buf[:n])
+		if err != nil {
+			l.error(err)
+			continue
+		}
+		l.emit(entry)
+	}
+
+}
+
+// ServeSyslogTCP accepts connections until ctx is cancelled. Both octet
+// counted and newline delimited framing from RFC 6587 are understood.
+func (l *Listener) ServeSyslogTCP(ctx context.Context, ln net.Listener) error {
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		go func() {
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-ctx.Done():
+				case <-done:
+				}
+				conn.Close()
+			}()
+
+			if err := l.readSyslogStream(conn); err != nil && ctx.Err() == nil {
+				l.error(err)
+			}
+		}()
+	}
+
+}
+
+func (l *Listener) readSyslogStream(r io.Reader) error {
+
+	reader := bufio.NewReader(r)
+	for {
+		message, err := readFrame(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(message) == 0 {
+			continue
+		}
+
+		entry, err := ParseSyslog(message)
+		if err != nil {
+			l.error(err)
+			continue
+		}
+		l.emit(entry)
+	}
+
+}
+
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] < '0' || first[0] > '9' {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			return line, nil
+		}
+		return line, err
+	}
+
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil || n <= 0 || n > 1024*1024 {
+		return nil, fmt.Errorf("invalid syslog frame length: %q", length)
+	}
+
+	message := make([]byte, n)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+
+}
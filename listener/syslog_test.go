@@ -0,0 +1,167 @@
+package listener
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+func TestParseSyslog(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		message   string
+		want      omada.LogEntry
+		wantTime  time.Time
+		wantStamp string
+	}{
+		{
+			name:    "rfc 5424",
+			message: "<134>1 2024-03-01T10:20:30.5Z OC200 omada - CLIENT_CONNECTED - client AA:BB:CC:DD:EE:01 connected to ap 11-22-33-44-55-66\n",
+			want: omada.LogEntry{
+				Kind:      omada.LogKindSyslog,
+				Level:     omada.LogLevelInfo,
+				Host:      "OC200",
+				Module:    "omada",
+				Key:       "CLIENT_CONNECTED",
+				Content:   "client AA:BB:CC:DD:EE:01 connected to ap 11-22-33-44-55-66",
+				ClientMac: "AA-BB-CC-DD-EE-01",
+				DeviceMac: "11-22-33-44-55-66",
+			},
+			wantTime: time.Date(2024, 3, 1, 10, 20, 30, 500000000, time.UTC),
+		},
+		{
+			name:    "rfc 5424 structured data and nil values",
+			message: `<11>1 - - - - - [meta x="a]b" y="c"][other] ` + "\ufeff" + "gateway failed",
+			want: omada.LogEntry{
+				Kind:    omada.LogKindSyslog,
+				Level:   omada.LogLevelError,
+				Content: "gateway failed",
+			},
+		},
+		{
+			name:    "rfc 3164",
+			message: "<12>Mar  1 10:20:30 OC200 omada[123]: switch aa-bb-cc-dd-ee-02 went offline",
+			want: omada.LogEntry{
+				Kind:      omada.LogKindSyslog,
+				Level:     omada.LogLevelWarning,
+				Host:      "OC200",
+				Module:    "omada",
+				Content:   "switch aa-bb-cc-dd-ee-02 went offline",
+				DeviceMac: "AA-BB-CC-DD-EE-02",
+			},
+			wantStamp: "Mar  1 10:20:30",
+		},
+		{
+			name:    "rfc 3164 without a tag",
+			message: "<14>Dec 31 23:59:59 eap some free text",
+			want: omada.LogEntry{
+				Kind:    omada.LogKindSyslog,
+				Level:   omada.LogLevelInfo,
+				Host:    "eap",
+				Content: "some free text",
+			},
+			wantStamp: "Dec 31 23:59:59",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := ParseSyslog([]byte(test.message))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case !test.wantTime.IsZero():
+				if !entry.Time.Equal(test.wantTime) {
+					t.Errorf("got time %s, want %s", entry.Time, test.wantTime)
+				}
+			case test.wantStamp != "":
+				if got := entry.Time.Format(time.Stamp); got != test.wantStamp {
+					t.Errorf("got time %s, want %s", got, test.wantStamp)
+				}
+				if entry.Time.After(time.Now().Add(24 * time.Hour)) {
+					t.Errorf("time %s is in the future", entry.Time)
+				}
+			}
+
+			entry.Time = time.Time{}
+			if entry != test.want {
+				t.Errorf("got  %+v\nwant %+v", entry, test.want)
+			}
+		})
+	}
+
+}
+
+func TestParseSyslogInvalid(t *testing.T) {
+
+	for _, message := range []string{
+		"",
+		"no priority",
+		"<>1 - - - - - x",
+		"<999>1 - - - - - x",
+		"<13>1 not-a-time host app - - - x",
+		"<13>1 - host",
+		"<13>short",
+		"<13>Foo 99 99:99:99 host x",
+	} {
+		if _, err := ParseSyslog([]byte(message)); err == nil {
+			t.Errorf("%q: expected an error", message)
+		}
+	}
+
+}
+
+func TestReadFrame(t *testing.T) {
+
+	// octet counted, newline delimited, then octet counted again
+	stream := "17 <13>1 - - - first<13>second\n5 <13>x"
+	reader := bufio.NewReader(strings.NewReader(stream))
+
+	var frames []string
+	for {
+		frame, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+		frames = append(frames, strings.TrimRight(string(frame), "\n"))
+	}
+
+	want := []string{"<13>1 - - - first", "<13>second", "<13>x"}
+	if strings.Join(frames, "|") != strings.Join(want, "|") {
+		t.Errorf("got frames %q, want %q", frames, want)
+	}
+
+}
+
+func TestParseWebhook(t *testing.T) {
+
+	body := `{"Site":"Home","description":"Client Connected","text":["client AA-BB-CC-DD-EE-01 joined"," second "],"timestamp":1709288430000,"shardSecret":"s3cret"}`
+
+	entries, err := ParseWebhook([]byte(body), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].SiteName != "Home" || entries[0].SiteId != "" || entries[0].Host != "" {
+		t.Errorf("got site name %q id %q host %q", entries[0].SiteName, entries[0].SiteId, entries[0].Host)
+	}
+	if entries[0].ClientMac != "AA-BB-CC-DD-EE-01" || entries[1].Content != "second" {
+		t.Errorf("got %+v", entries)
+	}
+	if !entries[0].Time.Equal(time.UnixMilli(1709288430000)) {
+		t.Errorf("got time %s", entries[0].Time)
+	}
+
+	if _, err := ParseWebhook([]byte(body), "other"); err != ErrInvalidSecret {
+		t.Errorf("got %v, want %v", err, ErrInvalidSecret)
+	}
+
+}
@@ -0,0 +1,103 @@
+package listener
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	omada "github.com/dougbw/go-omada"
+)
+
+var ErrInvalidSecret = errors.New("webhook shared secret does not match")
+
+type webhookPayload struct {
+	Site         string   `json:"Site"`
+	Description  string   `json:"description"`
+	Text         []string `json:"text"`
+	Controller   string   `json:"Controller"`
+	Timestamp    int64    `json:"timestamp"`
+	SharedSecret string   `json:"shardSecret"`
+}
+
+// ParseWebhook parses a webhook body, returning one entry per line of text.
+// When secret is not empty the payload must carry the same shared secret.
+func ParseWebhook(body []byte, secret string) ([]omada.LogEntry, error) {
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	if secret != "" && subtle.ConstantTimeCompare([]byte(payload.SharedSecret), []byte(secret)) != 1 {
+		return nil, ErrInvalidSecret
+	}
+
+	t := time.Now()
+	if payload.Timestamp != 0 {
+		t = time.UnixMilli(payload.Timestamp)
+	}
+
+	lines := payload.Text
+	if len(lines) == 0 {
+		lines = []string{payload.Description}
+	}
+
+	var entries []omada.LogEntry
+	for _, line := range lines {
+		entry := omada.LogEntry{
+			Kind:     omada.LogKindWebhook,
+			SiteName: payload.Site,
+			Module:   payload.Description,
+			Level:    omada.LogLevelWarning,
+			Content:  strings.TrimSpace(line),
+			Time:     t,
+		}
+		linkMacs(&entry)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+
+}
+
+func (l *Listener) WebhookHandler() http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+		if err != nil {
+			http.Error(w, "unable to read body", http.StatusBadRequest)
+			return
+		}
+
+		entries, err := ParseWebhook(body, l.opts.Secret)
+		if errors.Is(err, ErrInvalidSecret) {
+			l.error(err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			l.error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, entry := range entries {
+			l.emit(entry)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+}
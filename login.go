@@ -2,6 +2,7 @@ package omada
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -19,6 +20,8 @@ type Controller struct {
 	token        string
 	siteId       string
 	allSiteIds   []string
+	namer        Namer
+	ctx          context.Context
 }
 
 type ControllerInfo struct {
@@ -269,3 +272,43 @@ func (c *Controller) getAllSiteIds() error {
 	return nil
 
 }
+
+func (c *Controller) SiteIds() []string {
+	return append([]string{}, c.allSiteIds...)
+}
+
+// WithSite returns a copy of the controller that targets another site the
+// logged in user has access to. The copy shares the session, so all the site
+// scoped methods can be used against every site without logging in again.
+func (c *Controller) WithSite(siteId string) (*Controller, error) {
+
+	for _, v := range c.allSiteIds {
+		if v == siteId {
+			site := *c
+			site.siteId = siteId
+			return &site, nil
+		}
+	}
+
+	return nil, fmt.Errorf("site not found: %s", siteId)
+
+}
+
+// WithContext returns a copy of the controller whose api requests are bound
+// to ctx, so cancelling ctx aborts a request that is already in flight.
+func (c *Controller) WithContext(ctx context.Context) *Controller {
+
+	bound := *c
+	bound.ctx = ctx
+	return &bound
+
+}
+
+func (c *Controller) context() context.Context {
+
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+
+}
@@ -0,0 +1,249 @@
+package omada
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	LogLevelError   = "Error"
+	LogLevelWarning = "Warning"
+	LogLevelInfo    = "Info"
+)
+
+const (
+	LogKindEvent   = "events"
+	LogKindAlert   = "alerts"
+	LogKindSyslog  = "syslog"
+	LogKindWebhook = "webhook"
+)
+
+type LogQuery struct {
+	Page     int
+	PageSize int
+	Start    time.Time
+	End      time.Time
+	Level    string
+	Module   string
+	Type     string
+	Archived *bool
+}
+
+// LogEntry is an event or alert read from the api, or received through the
+// listener package. Each source only knows part of where the entry came from:
+// the api sets SiteId, webhooks set SiteName and syslog sets Host to the
+// sending controller or device.
+type LogEntry struct {
+	Id        string
+	Kind      string
+	SiteId    string
+	SiteName  string
+	Host      string
+	Key       string
+	Type      string
+	Module    string
+	Level     string
+	Content   string
+	Time      time.Time
+	DeviceMac string
+	ClientMac string
+	Archived  bool
+}
+
+type LogPage struct {
+	Entries     []LogEntry
+	TotalRows   int
+	CurrentPage int
+	PageSize    int
+}
+
+func (p LogPage) HasMore() bool {
+	return p.CurrentPage*p.PageSize < p.TotalRows
+}
+
+type logEntryWire struct {
+	Id        string `json:"id"`
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Module    string `json:"module"`
+	Level     string `json:"level"`
+	Content   string `json:"content"`
+	Time      int64  `json:"time"`
+	ApMac     string `json:"apMac"`
+	SwMac     string `json:"swMac"`
+	GwMac     string `json:"gwMac"`
+	ClientMac string `json:"clientMac"`
+	Archived  bool   `json:"archived"`
+}
+
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+
+	var wire logEntryWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	*e = LogEntry{
+		Id:        wire.Id,
+		Key:       wire.Key,
+		Type:      wire.Type,
+		Module:    wire.Module,
+		Level:     wire.Level,
+		Content:   wire.Content,
+		ClientMac: wire.ClientMac,
+		Archived:  wire.Archived,
+	}
+
+	if wire.Time != 0 {
+		e.Time = time.UnixMilli(wire.Time)
+	}
+
+	for _, mac := range []string{wire.ApMac, wire.SwMac, wire.GwMac} {
+		if mac != "" {
+			e.DeviceMac = mac
+			break
+		}
+	}
+
+	return nil
+
+}
+
+func (e LogEntry) Device(devices []Device) (Device, bool) {
+
+	if e.DeviceMac == "" {
+		return Device{}, false
+	}
+
+	for _, device := range devices {
+		if normalizeMac(device.Mac) == normalizeMac(e.DeviceMac) {
+			return device, true
+		}
+	}
+
+	return Device{}, false
+
+}
+
+func (e LogEntry) Client(clients []Client) (Client, bool) {
+
+	if e.ClientMac == "" {
+		return Client{}, false
+	}
+
+	for _, client := range clients {
+		if normalizeMac(client.MAC) == normalizeMac(e.ClientMac) {
+			return client, true
+		}
+	}
+
+	return Client{}, false
+
+}
+
+type logIds struct {
+	LogIds []string `json:"logIds"`
+}
+
+func (q LogQuery) path(kind string) string {
+
+	params := url.Values{}
+	if !q.Start.IsZero() {
+		params.Set("filters.timeStart", strconv.FormatInt(q.Start.UnixMilli(), 10))
+	}
+	if !q.End.IsZero() {
+		params.Set("filters.timeEnd", strconv.FormatInt(q.End.UnixMilli(), 10))
+	}
+	if q.Level != "" {
+		params.Set("filters.level", q.Level)
+	}
+	if q.Module != "" {
+		params.Set("filters.module", q.Module)
+	}
+	if q.Type != "" {
+		params.Set("filters.type", q.Type)
+	}
+	if q.Archived != nil {
+		params.Set("filters.archived", strconv.FormatBool(*q.Archived))
+	}
+
+	if len(params) == 0 {
+		return "logs/" + kind
+	}
+	return "logs/" + kind + "?" + params.Encode()
+
+}
+
+func (c *Controller) GetEvents(query LogQuery) (LogPage, error) {
+	return c.getLogs(LogKindEvent, query)
+}
+
+func (c *Controller) GetAlerts(query LogQuery) (LogPage, error) {
+	return c.getLogs(LogKindAlert, query)
+}
+
+func (c *Controller) getLogs(kind string, query LogQuery) (LogPage, error) {
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := query.PageSize
+	if pageSize < 1 {
+		pageSize = 100
+	}
+
+	if !query.Start.IsZero() && !query.End.IsZero() && query.End.Before(query.Start) {
+		return LogPage{}, fmt.Errorf("log query end %s is before start %s", query.End, query.Start)
+	}
+
+	var entries []LogEntry
+	paged, err := c.getPage(c.siteId, query.path(kind), page, pageSize, &entries)
+	if err != nil {
+		return LogPage{}, err
+	}
+
+	for i := range entries {
+		entries[i].Kind = kind
+		entries[i].SiteId = c.siteId
+	}
+
+	return LogPage{
+		Entries:     entries,
+		TotalRows:   paged.TotalRows,
+		CurrentPage: page,
+		PageSize:    pageSize,
+	}, nil
+
+}
+
+func (c *Controller) ArchiveAlerts(ids []string) error {
+
+	url := c.siteURL(c.siteId, "cmd/alerts/archive")
+	return c.doRequest("POST", url, logIds{LogIds: ids}, nil)
+
+}
+
+func (c *Controller) ArchiveAllAlerts() error {
+
+	url := c.siteURL(c.siteId, "cmd/alerts/archiveAll")
+	return c.doRequest("POST", url, nil, nil)
+
+}
+
+func (c *Controller) DeleteEvents(ids []string) error {
+
+	url := c.siteURL(c.siteId, "cmd/events/delete")
+	return c.doRequest("POST", url, logIds{LogIds: ids}, nil)
+
+}
+
+func (c *Controller) DeleteAlerts(ids []string) error {
+
+	url := c.siteURL(c.siteId, "cmd/alerts/delete")
+	return c.doRequest("POST", url, logIds{LogIds: ids}, nil)
+
+}
@@ -0,0 +1,406 @@
+package omada
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	maxLabelLength  = 63
+	maxSuffixLength = 24
+)
+
+type CollisionSuffix int
+
+const (
+	// CollisionSuffixMac appends the last six hex digits of the mac, or the
+	// whole mac if that is not enough to tell the hosts apart.
+	CollisionSuffixMac CollisionSuffix = iota
+	// CollisionSuffixSite appends the site name, falling back to the mac for
+	// hosts that collide within the same site.
+	CollisionSuffixSite
+)
+
+// NameCandidate is what a NameStrategy gets to build a label from.
+type NameCandidate struct {
+	Kind     string
+	Name     string
+	HostName string
+	Mac      string
+	SiteId   string
+}
+
+// NameStrategy turns a client or device into the dns label it should be
+// published under. Returning an empty label makes the Namer fall back to a
+// mac based name. Labels do not need to be unique, collisions are resolved
+// afterwards.
+type NameStrategy interface {
+	Label(candidate NameCandidate) string
+}
+
+// DefaultNameStrategy uses the name set on the controller, then the host
+// name the client reported, then <kind>-<mac>. Non-ascii names are
+// transliterated to ascii, or encoded as punycode when Punycode is set or
+// when they use a script with no ascii equivalent.
+type DefaultNameStrategy struct {
+	Punycode bool
+}
+
+func (s DefaultNameStrategy) Label(candidate NameCandidate) string {
+
+	for _, name := range []string{candidate.Name, candidate.HostName} {
+		if label := dnsLabel(name, s.Punycode); label != "" {
+			return label
+		}
+	}
+
+	return macLabel(candidate)
+
+}
+
+type NameCollision struct {
+	Label    string
+	Macs     []string
+	SiteIds  []string
+	Resolved []string
+}
+
+// Namer assigns unique, rfc 1123 compliant labels. The zero value uses
+// DefaultNameStrategy and resolves collisions with a mac suffix.
+type Namer struct {
+	Strategy NameStrategy
+	Suffix   CollisionSuffix
+
+	// SiteNames maps site ids to the names used by CollisionSuffixSite,
+	// site ids are used as is when missing.
+	SiteNames map[string]string
+
+	// AcrossSites resolves collisions between hosts of different sites in
+	// GetAllHosts, GetAllClients and GetAllDevices, rather than only within
+	// each site.
+	AcrossSites bool
+
+	OnCollision func(NameCollision)
+}
+
+// Assign returns a label for each candidate, in the same order, and the
+// collisions that had to be resolved. Hosts sharing a label all get a
+// suffix so a name never moves from one host to another as hosts come and
+// go.
+func (n Namer) Assign(candidates []NameCandidate) ([]string, []NameCollision) {
+	return n.assign(candidates, nil)
+}
+
+// assign is Assign with labels that are already in use by hosts that are
+// not among the candidates. A candidate wanting a reserved label is treated
+// as colliding with it.
+func (n Namer) assign(candidates []NameCandidate, reserved map[string]bool) ([]string, []NameCollision) {
+
+	strategy := n.Strategy
+	if strategy == nil {
+		strategy = DefaultNameStrategy{}
+	}
+
+	labels := make([]string, len(candidates))
+	groups := make(map[string][]int)
+	for i, candidate := range candidates {
+		label := strategy.Label(candidate)
+		if !validLabel(label) {
+			label = dnsLabel(label, false)
+		}
+		if label == "" {
+			label = macLabel(candidate)
+		}
+		labels[i] = label
+		groups[label] = append(groups[label], i)
+	}
+
+	taken := make(map[string]bool)
+	for label := range reserved {
+		taken[label] = true
+	}
+	var collided []string
+	for label, indexes := range groups {
+		if len(indexes) == 1 && !reserved[label] {
+			taken[label] = true
+			continue
+		}
+		collided = append(collided, label)
+	}
+	sort.Strings(collided)
+
+	var collisions []NameCollision
+	for _, label := range collided {
+		indexes := groups[label]
+		sort.SliceStable(indexes, func(i, j int) bool {
+			a, b := candidates[indexes[i]], candidates[indexes[j]]
+			if normalizeMac(a.Mac) != normalizeMac(b.Mac) {
+				return normalizeMac(a.Mac) < normalizeMac(b.Mac)
+			}
+			return a.SiteId < b.SiteId
+		})
+
+		collision := NameCollision{Label: label}
+		for _, i := range indexes {
+			labels[i] = n.resolveCollision(label, candidates[i], candidates, indexes, taken)
+			taken[labels[i]] = true
+
+			collision.Macs = append(collision.Macs, candidates[i].Mac)
+			collision.SiteIds = append(collision.SiteIds, candidates[i].SiteId)
+			collision.Resolved = append(collision.Resolved, labels[i])
+		}
+
+		if n.OnCollision != nil {
+			n.OnCollision(collision)
+		}
+		collisions = append(collisions, collision)
+	}
+
+	return labels, collisions
+
+}
+
+func (n Namer) resolveCollision(label string, candidate NameCandidate, candidates []NameCandidate, group []int, taken map[string]bool) string {
+
+	var suffixes []string
+	if n.Suffix == CollisionSuffixSite && candidate.SiteId != "" {
+		sameSite := 0
+		for _, i := range group {
+			if candidates[i].SiteId == candidate.SiteId {
+				sameSite++
+			}
+		}
+		if sameSite == 1 {
+			site := candidate.SiteId
+			if name, ok := n.SiteNames[site]; ok {
+				site = name
+			}
+			suffixes = append(suffixes, dnsLabel(site, false))
+		}
+	}
+
+	mac := strings.ToLower(strings.ReplaceAll(normalizeMac(candidate.Mac), "-", ""))
+	if len(mac) > 6 {
+		suffixes = append(suffixes, mac[len(mac)-6:])
+	}
+	suffixes = append(suffixes, mac)
+
+	for _, suffix := range suffixes {
+		if suffix == "" {
+			continue
+		}
+		resolved := withSuffix(label, suffix)
+		if !taken[resolved] {
+			return resolved
+		}
+	}
+
+	// only reached for hosts without a mac, or with duplicate macs
+	for i := 2; ; i++ {
+		resolved := withSuffix(label, fmt.Sprint(i))
+		if !taken[resolved] {
+			return resolved
+		}
+	}
+
+}
+
+// nameHosts sets DnsName on clients and devices, resolving collisions over
+// both lists together.
+func (n Namer) nameHosts(clients []Client, devices []Device) {
+
+	candidates := make([]NameCandidate, 0, len(clients)+len(devices))
+	for _, client := range clients {
+		candidates = append(candidates, NameCandidate{
+			Kind:     HostKindClient,
+			Name:     client.Name,
+			HostName: client.HostName,
+			Mac:      client.MAC,
+			SiteId:   client.SiteId,
+		})
+	}
+	for _, device := range devices {
+		candidates = append(candidates, NameCandidate{
+			Kind:   HostKindDevice,
+			Name:   device.Name,
+			Mac:    device.Mac,
+			SiteId: device.SiteId,
+		})
+	}
+
+	labels, _ := n.Assign(candidates)
+	for i := range clients {
+		clients[i].DnsName = labels[i]
+	}
+	for i := range devices {
+		devices[i].DnsName = labels[len(clients)+i]
+	}
+
+}
+
+// SetNamer changes how DnsName is built for clients and devices.
+func (c *Controller) SetNamer(namer Namer) {
+	c.namer = namer
+}
+
+// transliterations covers latin letters that do not decompose into an ascii
+// letter and an accent.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ħ': "h", 'ŧ': "t",
+}
+
+// dnsLabel turns input into a single lower case dns label. Accents are
+// stripped, whitespace becomes a hyphen and anything else that is not a
+// letter, digit or hyphen is dropped. Letters with no ascii equivalent, such
+// as cyrillic or cjk, are kept and the label is idna encoded instead, as are
+// all non-ascii letters when punycode is set.
+func dnsLabel(input string, punycode bool) string {
+
+	label, folded := asciiLabel(input)
+	if !punycode && folded {
+		return label
+	}
+
+	if encoded := punycodeLabel(input); encoded != "" {
+		return encoded
+	}
+
+	return label
+
+}
+
+// asciiLabel folds input to ascii, reporting false if any letter or digit
+// had to be dropped.
+func asciiLabel(input string) (string, bool) {
+
+	// decomposing splits accents off their letters so they can be dropped
+	var sb strings.Builder
+	folded := true
+	for _, r := range norm.NFKD.String(input) {
+		r = unicode.ToLower(r)
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			sb.WriteRune(r)
+		case r == '-' || unicode.IsSpace(r):
+			sb.WriteRune('-')
+		case transliterations[r] != "":
+			sb.WriteString(transliterations[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			folded = false
+		}
+	}
+
+	return truncateLabel(collapseHyphens(sb.String())), folded
+
+}
+
+// punycodeLabel keeps non-ascii letters composed and idna encodes them,
+// dropping trailing characters until the encoded label fits.
+func punycodeLabel(input string) string {
+
+	var runes []rune
+	for _, r := range norm.NFKC.String(input) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			runes = append(runes, unicode.ToLower(r))
+		case r == '-' || unicode.IsSpace(r):
+			runes = append(runes, '-')
+		}
+	}
+
+	for len(runes) > 0 {
+		label := collapseHyphens(string(runes))
+		encoded, err := idna.Punycode.ToASCII(label)
+		if err == nil && validLabel(encoded) {
+			return encoded
+		}
+		if err != nil {
+			return ""
+		}
+		runes = runes[:len(runes)-1]
+	}
+
+	return ""
+
+}
+
+func validLabel(label string) bool {
+
+	if label == "" || len(label) > maxLabelLength {
+		return false
+	}
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return false
+	}
+
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+func collapseHyphens(label string) string {
+
+	for strings.Contains(label, "--") {
+		label = strings.ReplaceAll(label, "--", "-")
+	}
+
+	return strings.Trim(label, "-")
+
+}
+
+func truncateLabel(label string) string {
+	if len(label) > maxLabelLength {
+		label = label[:maxLabelLength]
+	}
+	return strings.Trim(label, "-")
+}
+
+// withSuffix appends suffix to label, shortening label so the result still
+// fits in a single dns label. Suffixes longer than maxSuffixLength, such as
+// long site names, are cut down and keep a short hash of the full suffix so
+// distinct suffixes stay distinct.
+func withSuffix(label string, suffix string) string {
+
+	if len(suffix) > maxSuffixLength {
+		sum := fnv.New32a()
+		sum.Write([]byte(suffix))
+		hash := fmt.Sprintf("%08x", sum.Sum32())[:6]
+		suffix = strings.TrimRight(suffix[:maxSuffixLength-len(hash)-1], "-") + "-" + hash
+	}
+
+	max := maxLabelLength - len(suffix) - 1
+	if len(label) > max {
+		label = strings.TrimRight(label[:max], "-")
+	}
+
+	return label + "-" + suffix
+
+}
+
+func macLabel(candidate NameCandidate) string {
+
+	kind := candidate.Kind
+	if kind == "" {
+		kind = "host"
+	}
+
+	mac := strings.ToLower(strings.ReplaceAll(normalizeMac(candidate.Mac), "-", ""))
+	if mac == "" {
+		return kind
+	}
+
+	return kind + "-" + mac
+
+}
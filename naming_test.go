@@ -0,0 +1,260 @@
+package omada
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamerAssign(t *testing.T) {
+
+	long := strings.Repeat("a", 70)
+	longSite := strings.Repeat("s", 62)
+
+	tests := []struct {
+		name       string
+		namer      Namer
+		candidates []NameCandidate
+		want       []string
+		collisions int
+	}{
+		{
+			name: "unique names",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "Living Room TV", Mac: "AA-BB-CC-00-00-01"},
+				{Kind: HostKindClient, Name: "nas", Mac: "AA-BB-CC-00-00-02"},
+			},
+			want: []string{"living-room-tv", "nas"},
+		},
+		{
+			name: "falls back to host name then mac",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "", HostName: "laptop.local", Mac: "AA-BB-CC-00-00-01"},
+				{Kind: HostKindClient, Name: "!!!", HostName: "", Mac: "aa:bb:cc:00:00:02"},
+				{Kind: HostKindDevice, Mac: "AA-BB-CC-00-00-03"},
+				{Name: ""},
+			},
+			want: []string{"laptoplocal", "client-aabbcc000002", "device-aabbcc000003", "host"},
+		},
+		{
+			name: "long names are truncated",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: long, Mac: "AA-BB-CC-00-00-01"},
+			},
+			want: []string{strings.Repeat("a", 63)},
+		},
+		{
+			name: "collisions all get a mac suffix",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "Phone", Mac: "AA-BB-CC-00-00-02"},
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01"},
+			},
+			want:       []string{"phone-000002", "phone-000001"},
+			collisions: 1,
+		},
+		{
+			name: "same short mac suffix falls back to the full mac",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01"},
+				{Kind: HostKindClient, Name: "phone", Mac: "11-22-33-00-00-01"},
+			},
+			want:       []string{"phone-aabbcc000001", "phone-000001"},
+			collisions: 1,
+		},
+		{
+			name: "duplicate macs fall back to a counter",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01", SiteId: "a"},
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01", SiteId: "b"},
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01", SiteId: "c"},
+			},
+			want:       []string{"phone-000001", "phone-aabbcc000001", "phone-2"},
+			collisions: 1,
+		},
+		{
+			name: "suffixed long names stay within 63 bytes",
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: long, Mac: "AA-BB-CC-00-00-01"},
+				{Kind: HostKindClient, Name: long, Mac: "AA-BB-CC-00-00-02"},
+			},
+			want: []string{
+				strings.Repeat("a", 56) + "-000001",
+				strings.Repeat("a", 56) + "-000002",
+			},
+			collisions: 1,
+		},
+		{
+			name:  "site suffix",
+			namer: Namer{Suffix: CollisionSuffixSite, SiteNames: map[string]string{"1": "Head Office"}},
+			candidates: []NameCandidate{
+				{Kind: HostKindDevice, Name: "gateway", Mac: "AA-BB-CC-00-00-01", SiteId: "1"},
+				{Kind: HostKindDevice, Name: "gateway", Mac: "AA-BB-CC-00-00-02", SiteId: "2"},
+			},
+			want:       []string{"gateway-head-office", "gateway-2"},
+			collisions: 1,
+		},
+		{
+			name:  "site suffix falls back to the mac within one site",
+			namer: Namer{Suffix: CollisionSuffixSite},
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01", SiteId: "home"},
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-02", SiteId: "home"},
+				{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-03", SiteId: "office"},
+			},
+			want:       []string{"phone-000001", "phone-000002", "phone-office"},
+			collisions: 1,
+		},
+		{
+			name:  "long site names are shortened",
+			namer: Namer{Suffix: CollisionSuffixSite, SiteNames: map[string]string{"1": longSite, "2": longSite + "x"}},
+			candidates: []NameCandidate{
+				{Kind: HostKindClient, Name: long, Mac: "AA-BB-CC-00-00-01", SiteId: "1"},
+				{Kind: HostKindClient, Name: long, Mac: "AA-BB-CC-00-00-02", SiteId: "2"},
+			},
+			collisions: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labels, collisions := test.namer.Assign(test.candidates)
+
+			if len(labels) != len(test.candidates) {
+				t.Fatalf("got %d labels for %d candidates", len(labels), len(test.candidates))
+			}
+			seen := make(map[string]bool)
+			for _, label := range labels {
+				if !validLabel(label) {
+					t.Errorf("invalid label %q", label)
+				}
+				if seen[label] {
+					t.Errorf("label %q handed out twice", label)
+				}
+				seen[label] = true
+			}
+
+			if test.want != nil && strings.Join(labels, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got labels %v, want %v", labels, test.want)
+			}
+			if len(collisions) != test.collisions {
+				t.Errorf("got %d collisions, want %d", len(collisions), test.collisions)
+			}
+		})
+	}
+
+}
+
+func TestNamerAssignStable(t *testing.T) {
+
+	a := NameCandidate{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-01"}
+	b := NameCandidate{Kind: HostKindClient, Name: "phone", Mac: "AA-BB-CC-00-00-02"}
+
+	forward, _ := Namer{}.Assign([]NameCandidate{a, b})
+	reverse, _ := Namer{}.Assign([]NameCandidate{b, a})
+
+	if forward[0] != reverse[1] || forward[1] != reverse[0] {
+		t.Errorf("labels depend on order: %v and %v", forward, reverse)
+	}
+
+}
+
+func TestNamerOnCollision(t *testing.T) {
+
+	var got []NameCollision
+	namer := Namer{OnCollision: func(collision NameCollision) {
+		got = append(got, collision)
+	}}
+
+	namer.Assign([]NameCandidate{
+		{Name: "x", Mac: "AA-BB-CC-00-00-01"},
+		{Name: "x", Mac: "AA-BB-CC-00-00-02"},
+	})
+
+	if len(got) != 1 || got[0].Label != "x" || len(got[0].Resolved) != 2 {
+		t.Errorf("got collisions %+v", got)
+	}
+
+}
+
+func TestWithSuffix(t *testing.T) {
+
+	tests := []struct {
+		label  string
+		suffix string
+	}{
+		{"phone", "abc123"},
+		{strings.Repeat("a", 63), "abc123"},
+		{strings.Repeat("a", 63), strings.Repeat("b", 62)},
+		{strings.Repeat("a", 63), strings.Repeat("b", 63)},
+		{"a-b-c-d-e-f-g-h-i-j-k-l-m-n-o-p-q-r-s-t-u-v-w-x-y-z-a-b-c-d-e-f", "z-y-x-w-v-u-t-s-r-q-p-o-n-m-l-k-j"},
+	}
+
+	for _, test := range tests {
+		got := withSuffix(test.label, test.suffix)
+		if !validLabel(got) {
+			t.Errorf("withSuffix(%q, %q) = %q is not a valid label", test.label, test.suffix, got)
+		}
+	}
+
+	if withSuffix("x", strings.Repeat("b", 40)+"1") == withSuffix("x", strings.Repeat("b", 40)+"2") {
+		t.Error("shortened suffixes should stay distinct")
+	}
+
+}
+
+func TestNameHostsSharesNamespace(t *testing.T) {
+
+	clients := []Client{{Name: "office", MAC: "AA-BB-CC-00-00-01"}}
+	devices := []Device{{Name: "Office", Mac: "AA-BB-CC-00-00-02"}}
+
+	Namer{}.nameHosts(clients, devices)
+
+	if clients[0].DnsName != "office-000001" || devices[0].DnsName != "office-000002" {
+		t.Errorf("got client %q device %q", clients[0].DnsName, devices[0].DnsName)
+	}
+
+}
+
+func TestNamerAssignReserved(t *testing.T) {
+
+	candidates := []NameCandidate{
+		{Kind: HostKindClient, Name: "office", Mac: "AA-BB-CC-00-00-01"},
+		{Kind: HostKindClient, Name: "laptop", Mac: "AA-BB-CC-00-00-02"},
+	}
+
+	labels, _ := Namer{}.assign(candidates, map[string]bool{"office": true})
+	if labels[0] != "office-000001" || labels[1] != "laptop" {
+		t.Errorf("got labels %v", labels)
+	}
+
+}
+
+func TestDnsLabel(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		punycode bool
+		want     string
+	}{
+		{"Living Room TV", false, "living-room-tv"},
+		{"Café Déjà-vu", false, "cafe-deja-vu"},
+		{"Straße Øresund Łódź", false, "strasse-oresund-lodz"},
+		{"Иван", false, "xn--80adrw"},
+		{"Телефон Ивана", false, "xn----8sbagnawvtef8b3a"},
+		{"客厅", false, "xn--imrr2q"},
+		{"Café", true, "xn--caf-dma"},
+		{"plain", true, "plain"},
+		{"__--__", false, ""},
+	}
+
+	for _, test := range tests {
+		if got := dnsLabel(test.input, test.punycode); got != test.want {
+			t.Errorf("dnsLabel(%q, %v) = %q, want %q", test.input, test.punycode, got, test.want)
+		}
+	}
+
+	// long names are shortened before encoding so they still fit
+	if got := dnsLabel(strings.Repeat("ж", 60), false); !validLabel(got) || !strings.HasPrefix(got, "xn--") {
+		t.Errorf("got %q, want a shortened punycode label", got)
+	}
+
+}
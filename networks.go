@@ -1,9 +1,8 @@
 package omada
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"net/netip"
 	"sort"
 )
 
@@ -18,42 +17,91 @@ type GetNetworksResponse struct {
 	} `json:"result"`
 }
 
+const (
+	NetworkPurposeInterface = "interface"
+	NetworkPurposeVlan      = "vlan"
+)
+
 type OmadaNetwork struct {
-	Id     string `json:"id"`
-	Name   string `json:"name,omitempty"`
-	Domain string `json:"domain,omitempty"`
-	Subnet string `json:"gatewaySubnet"`
+	Id              string               `json:"id,omitempty"`
+	Name            string               `json:"name,omitempty"`
+	Domain          string               `json:"domain,omitempty"`
+	Subnet          string               `json:"gatewaySubnet"`
+	Site            string               `json:"site,omitempty"`
+	Purpose         string               `json:"purpose"`
+	Vlan            int                  `json:"vlan"`
+	InterfaceIds    []string             `json:"interfaceIds,omitempty"`
+	LagIds          []string             `json:"lagIds,omitempty"`
+	IgmpSnoopEnable bool                 `json:"igmpSnoopEnable"`
+	DhcpSettings    *NetworkDhcpSettings `json:"dhcpSettings,omitempty"`
+	DhcpGuard       *NetworkDhcpGuard    `json:"dhcpGuard,omitempty"`
+	Ipv6            *NetworkIpv6         `json:"lanNetworkIpv6Config,omitempty"`
+}
+
+type NetworkDhcpSettings struct {
+	Enable      bool                `json:"enable"`
+	IpaddrStart string              `json:"ipaddrStart,omitempty"`
+	IpaddrEnd   string              `json:"ipaddrEnd,omitempty"`
+	LeaseTime   int                 `json:"leasetime,omitempty"`
+	Dhcpns      string              `json:"dhcpns,omitempty"`
+	PriDns      string              `json:"priDns,omitempty"`
+	SndDns      string              `json:"sndDns,omitempty"`
+	Gateway     string              `json:"gateway,omitempty"`
+	Options     []NetworkDhcpOption `json:"options,omitempty"`
+}
+
+type NetworkDhcpOption struct {
+	Code  int    `json:"code"`
+	Type  int    `json:"type"`
+	Value string `json:"value"`
+}
+
+type NetworkDhcpGuard struct {
+	Enable   bool   `json:"enable"`
+	DhcpSvr1 string `json:"dhcpSvr1,omitempty"`
+	DhcpSvr2 string `json:"dhcpSvr2,omitempty"`
+}
+
+type NetworkIpv6 struct {
+	Proto        int    `json:"proto"`
+	Enable       bool   `json:"enable"`
+	Prefix       string `json:"prefix,omitempty"`
+	RaEnable     bool   `json:"raEnable"`
+	Dhcpv6Enable bool   `json:"dhcpv6Enable"`
+	PriDns       string `json:"priDns,omitempty"`
+	SndDns       string `json:"sndDns,omitempty"`
 }
 
 func (c *Controller) GetNetworks() ([]OmadaNetwork, error) {
+	return c.getSiteNetworks(c.siteId)
+}
 
-	url := fmt.Sprintf("%s/%s/api/v2/sites/%s/setting/lan/networks?currentPage=1&currentPageSize=999", c.baseURL, c.controllerId, c.siteId)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Add("Csrf-Token", c.token)
+func (c *Controller) GetAllNetworks() ([]OmadaNetwork, error) {
 
-	res, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	var allNetworks []OmadaNetwork
+	for _, v := range c.allSiteIds {
+		networks, err := c.getSiteNetworks(v)
+		if err != nil {
+			return nil, err
+		}
+		allNetworks = append(allNetworks, networks...)
 	}
 
-	if res.StatusCode != http.StatusOK {
-		err = fmt.Errorf("status code: %d", res.StatusCode)
-		return nil, err
-	}
+	sort.Slice(allNetworks, func(i, j int) bool {
+		return allNetworks[i].Name < allNetworks[j].Name
+	})
 
-	// respBody, _ := ioutil.ReadAll(res.Body)
-	// fmt.Println(string(respBody))
+	return allNetworks, nil
 
-	var networkResponse GetNetworksResponse
-	if err := json.NewDecoder(res.Body).Decode(&networkResponse); err != nil {
+}
+
+func (c *Controller) getSiteNetworks(siteId string) ([]OmadaNetwork, error) {
+
+	var networks []OmadaNetwork
+	if err := c.getPaged(siteId, "setting/lan/networks", &networks); err != nil {
 		return nil, err
 	}
 
-	networks := networkResponse.Result.Data
 	sort.Slice(networks, func(i, j int) bool {
 		return networks[i].Name < networks[j].Name
 	})
@@ -62,44 +110,157 @@ func (c *Controller) GetNetworks() ([]OmadaNetwork, error) {
 
 }
 
-func (c *Controller) GetAllNetworks() ([]OmadaNetwork, error) {
+func (c *Controller) NetworkIdsByName(names ...string) ([]string, error) {
 
-	var allNetworks []OmadaNetwork
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, v := range c.allSiteIds {
-		url := fmt.Sprintf("%s/%s/api/v2/sites/%s/setting/lan/networks?currentPage=1&currentPageSize=999", c.baseURL, c.controllerId, v)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("Accept", "application/json")
-		req.Header.Add("Csrf-Token", c.token)
+	ids := make(map[string]string)
+	for _, network := range networks {
+		ids[network.Name] = network.Id
+	}
 
-		res, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, err
+	var resolved []string
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("network not found: %s", name)
 		}
+		resolved = append(resolved, id)
+	}
 
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("status code: %d", res.StatusCode)
-			return nil, err
+	return resolved, nil
+
+}
+
+func (c *Controller) CreateNetwork(network OmadaNetwork) (OmadaNetwork, error) {
+
+	network.Id = ""
+	if err := c.checkNetwork(network); err != nil {
+		return OmadaNetwork{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/lan/networks")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, network, &created); err != nil {
+		return OmadaNetwork{}, err
+	}
+
+	network.Id = created.Id
+	return network, nil
+
+}
+
+func (c *Controller) UpdateNetwork(network OmadaNetwork) error {
+
+	if network.Id == "" {
+		return fmt.Errorf("network id is required")
+	}
+
+	if err := c.checkNetwork(network); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/lan/networks/"+network.Id)
+	return c.doRequest("PATCH", url, network, nil)
+
+}
+
+func (c *Controller) DeleteNetwork(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/lan/networks/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) checkNetwork(network OmadaNetwork) error {
+
+	existing, err := c.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	return validateNetwork(network, existing)
+
+}
+
+func validateNetwork(network OmadaNetwork, existing []OmadaNetwork) error {
+
+	if network.Name == "" {
+		return fmt.Errorf("network name is required")
+	}
+
+	prefix, err := netip.ParsePrefix(network.Subnet)
+	if err != nil {
+		return fmt.Errorf("network %s: invalid subnet: %s", network.Name, network.Subnet)
+	}
+
+	switch network.Purpose {
+	case NetworkPurposeInterface, "":
+	case NetworkPurposeVlan:
+		if network.Vlan < 1 || network.Vlan > 4094 {
+			return fmt.Errorf("network %s: vlan id must be between 1 and 4094, got: %d", network.Name, network.Vlan)
 		}
+	default:
+		return fmt.Errorf("network %s: unknown purpose: %s", network.Name, network.Purpose)
+	}
 
-		// respBody, _ := ioutil.ReadAll(res.Body)
-		// fmt.Println(string(respBody))
+	if err := validateDhcpSettings(network.Name, prefix, network.DhcpSettings); err != nil {
+		return err
+	}
 
-		var networkResponse GetNetworksResponse
-		if err := json.NewDecoder(res.Body).Decode(&networkResponse); err != nil {
-			return nil, err
+	for _, other := range existing {
+		if other.Id == network.Id {
+			continue
+		}
+		if network.Vlan != 0 && other.Vlan == network.Vlan {
+			return fmt.Errorf("network %s: vlan %d is already used by network %s", network.Name, network.Vlan, other.Name)
+		}
+		otherPrefix, err := other.Prefix()
+		if err != nil {
+			continue
 		}
+		if prefix.Masked().Overlaps(otherPrefix) {
+			return fmt.Errorf("network %s: subnet %s overlaps %s used by network %s", network.Name, network.Subnet, other.Subnet, other.Name)
+		}
+	}
 
-		networks := networkResponse.Result.Data
-		allNetworks = append(allNetworks, networks...)
+	return nil
 
-		sort.Slice(allNetworks, func(i, j int) bool {
-			return allNetworks[i].Name < allNetworks[j].Name
-		})
+}
+
+func validateDhcpSettings(name string, prefix netip.Prefix, dhcp *NetworkDhcpSettings) error {
 
+	if dhcp == nil || !dhcp.Enable {
+		return nil
 	}
-	return allNetworks, nil
+
+	start, err := netip.ParseAddr(dhcp.IpaddrStart)
+	if err != nil {
+		return fmt.Errorf("network %s: invalid dhcp range start: %s", name, dhcp.IpaddrStart)
+	}
+	end, err := netip.ParseAddr(dhcp.IpaddrEnd)
+	if err != nil {
+		return fmt.Errorf("network %s: invalid dhcp range end: %s", name, dhcp.IpaddrEnd)
+	}
+
+	if !prefix.Contains(start) || !prefix.Contains(end) {
+		return fmt.Errorf("network %s: dhcp range %s-%s is not within %s", name, start, end, prefix.Masked())
+	}
+	if end.Less(start) {
+		return fmt.Errorf("network %s: dhcp range end %s is before start %s", name, end, start)
+	}
+
+	if dhcp.Gateway != "" {
+		gateway, err := netip.ParseAddr(dhcp.Gateway)
+		if err != nil || !prefix.Contains(gateway) {
+			return fmt.Errorf("network %s: dhcp gateway %s is not within %s", name, dhcp.Gateway, prefix.Masked())
+		}
+	}
+
+	return nil
+
 }
@@ -0,0 +1,51 @@
+package omada
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetNetworks(t *testing.T) {
+
+	body := `{"errorCode":0,"result":{"data":[{"name":"lan","domain":"home.lan","gatewaySubnet":"10.0.0.1/24"},{"name":"guest","gatewaySubnet":"10.1.0.1/24"}]}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("currentPageSize") == "" {
+			t.Errorf("unpaged request %s", r.URL)
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "a", allSiteIds: []string{"a", "b"}}
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 2 || networks[0].Name != "guest" || networks[1].Domain != "home.lan" {
+		t.Errorf("got networks %+v", networks)
+	}
+
+	all, err := c.GetAllNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("got %d networks across sites, want 4", len(all))
+	}
+
+	body = `{"errorCode":-1200,"msg":"Login required"}`
+	if _, err := c.GetNetworks(); !IsAuthError(err) {
+		t.Errorf("got %v, want an auth error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.WithContext(ctx).GetNetworks(); err == nil {
+		t.Error("expected an error for a cancelled context")
+	}
+
+}
@@ -0,0 +1,291 @@
+package omada
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const (
+	ProtocolAll = 0
+	ProtocolTcp = 1
+	ProtocolUdp = 2
+)
+
+type PortForward struct {
+	Id               string   `json:"id,omitempty"`
+	Name             string   `json:"name"`
+	Status           bool     `json:"status"`
+	WanPortIds       []string `json:"interfaceWanPortId"`
+	ExternalPort     string   `json:"externalPort"`
+	ForwardIp        string   `json:"forwardIp"`
+	ForwardPort      string   `json:"forwardPort"`
+	Protocol         int      `json:"protocol"`
+	SrcIpLimitEnable bool     `json:"sourceIpLimitEnable"`
+	SrcIps           []string `json:"sourceIps,omitempty"`
+}
+
+// PortForwardBinding ties a rule to a client so RefreshPortForwards can keep
+// the rule pointed at whatever ip the client currently has. Rules created
+// with CreatePortForwardForClient carry their binding in their name and do
+// not need one.
+type PortForwardBinding struct {
+	RuleId string
+	Mac    string
+}
+
+func (p PortForward) Validate() error {
+
+	if p.Name == "" {
+		return fmt.Errorf("port forward name is required")
+	}
+
+	external, err := ParsePortRange(p.ExternalPort)
+	if err != nil {
+		return fmt.Errorf("port forward %s: external %w", p.Name, err)
+	}
+	internal, err := ParsePortRange(p.ForwardPort)
+	if err != nil {
+		return fmt.Errorf("port forward %s: forward %w", p.Name, err)
+	}
+	if external.Size() != internal.Size() {
+		return fmt.Errorf("port forward %s: external and forward port ranges must be the same size", p.Name)
+	}
+
+	if p.Protocol < ProtocolAll || p.Protocol > ProtocolUdp {
+		return fmt.Errorf("port forward %s: unknown protocol: %d", p.Name, p.Protocol)
+	}
+
+	if _, err := netip.ParseAddr(p.ForwardIp); err != nil {
+		return fmt.Errorf("port forward %s: invalid forward ip: %s", p.Name, p.ForwardIp)
+	}
+
+	for _, src := range p.SrcIps {
+		if _, err := netip.ParsePrefix(src); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(src); err != nil {
+			return fmt.Errorf("port forward %s: invalid source ip: %s", p.Name, src)
+		}
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetPortForwards() ([]PortForward, error) {
+
+	var rules []PortForward
+	if err := c.getPaged(c.siteId, "setting/transmission/portForwardings", &rules); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+
+	return rules, nil
+
+}
+
+func (c *Controller) GetPortForward(id string) (PortForward, error) {
+
+	rules, err := c.GetPortForwards()
+	if err != nil {
+		return PortForward{}, err
+	}
+
+	for _, rule := range rules {
+		if rule.Id == id {
+			return rule, nil
+		}
+	}
+
+	return PortForward{}, fmt.Errorf("port forward not found: %s", id)
+
+}
+
+func (c *Controller) CreatePortForward(rule PortForward) (PortForward, error) {
+
+	rule.Id = ""
+	if err := c.checkPortForward(rule); err != nil {
+		return PortForward{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/portForwardings")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, rule, &created); err != nil {
+		return PortForward{}, err
+	}
+
+	rule.Id = created.Id
+	return rule, nil
+
+}
+
+// CreatePortForwardForClient creates a rule forwarding to the client's
+// current ip. The client's mac is appended to the rule name, as in
+// "ssh [AA-BB-CC-DD-EE-FF]", so RefreshPortForwards can find the client
+// again.
+func (c *Controller) CreatePortForwardForClient(rule PortForward, mac string) (PortForward, error) {
+
+	ip, err := c.clientIp(mac)
+	if err != nil {
+		return PortForward{}, err
+	}
+
+	rule.ForwardIp = ip
+	rule.Name = fmt.Sprintf("%s [%s]", rule.Name, normalizeMac(mac))
+	return c.CreatePortForward(rule)
+
+}
+
+// PortForwardClientMac returns the client mac stored in the name of a rule
+// created with CreatePortForwardForClient.
+func PortForwardClientMac(rule PortForward) (string, bool) {
+
+	open := strings.LastIndex(rule.Name, " [")
+	if open < 0 || !strings.HasSuffix(rule.Name, "]") {
+		return "", false
+	}
+
+	mac := rule.Name[open+2 : len(rule.Name)-1]
+	if _, err := net.ParseMAC(mac); err != nil {
+		return "", false
+	}
+
+	return normalizeMac(mac), true
+
+}
+
+func (c *Controller) UpdatePortForward(rule PortForward) error {
+
+	if rule.Id == "" {
+		return fmt.Errorf("port forward id is required")
+	}
+
+	if err := c.checkPortForward(rule); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/portForwardings/"+rule.Id)
+	return c.doRequest("PATCH", url, rule, nil)
+
+}
+
+func (c *Controller) SetPortForwardEnabled(id string, enabled bool) error {
+
+	rule, err := c.GetPortForward(id)
+	if err != nil {
+		return err
+	}
+
+	rule.Status = enabled
+	return c.UpdatePortForward(rule)
+
+}
+
+func (c *Controller) DeletePortForward(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/transmission/portForwardings/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+// RefreshPortForwards points every rule created with
+// CreatePortForwardForClient, and any rule in bindings, at its client's
+// current ip and returns the rules that had to be changed. Rules whose
+// client is not connected are left alone.
+func (c *Controller) RefreshPortForwards(bindings ...PortForwardBinding) ([]PortForward, error) {
+
+	rules, err := c.GetPortForwards()
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := c.GetClients()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make(map[string]string)
+	for _, client := range clients {
+		ips[normalizeMac(client.MAC)] = client.Ip
+	}
+
+	macs := make(map[string]string)
+	for _, rule := range rules {
+		if mac, ok := PortForwardClientMac(rule); ok {
+			macs[rule.Id] = mac
+		}
+	}
+	for _, binding := range bindings {
+		macs[binding.RuleId] = normalizeMac(binding.Mac)
+	}
+
+	var updated []PortForward
+	for _, rule := range rules {
+		mac, ok := macs[rule.Id]
+		if !ok {
+			continue
+		}
+		ip, ok := ips[mac]
+		if !ok || rule.ForwardIp == ip {
+			continue
+		}
+		rule.ForwardIp = ip
+		if err := c.UpdatePortForward(rule); err != nil {
+			return updated, err
+		}
+		updated = append(updated, rule)
+	}
+
+	return updated, nil
+
+}
+
+func (c *Controller) checkPortForward(rule PortForward) error {
+
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	addr, _ := netip.ParseAddr(rule.ForwardIp)
+	if _, ok := NewNetworkResolver(networks).Lookup(addr); !ok {
+		return fmt.Errorf("port forward %s: forward ip %s is not within any lan network", rule.Name, rule.ForwardIp)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) clientIp(mac string) (string, error) {
+
+	clients, err := c.GetClients()
+	if err != nil {
+		return "", err
+	}
+
+	for _, client := range clients {
+		if normalizeMac(client.MAC) == normalizeMac(mac) {
+			return client.Ip, nil
+		}
+	}
+
+	return "", fmt.Errorf("client not found: %s", mac)
+
+}
+
+// normalizeMac allows macs to be given as aa:bb:cc:dd:ee:ff while the
+// controller reports them as AA-BB-CC-DD-EE-FF.
+func normalizeMac(mac string) string {
+	return strings.ToUpper(strings.ReplaceAll(mac, ":", "-"))
+}
@@ -0,0 +1,78 @@
+package omada
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPortForwardClientMac(t *testing.T) {
+
+	tests := []struct {
+		name string
+		mac  string
+		ok   bool
+	}{
+		{"ssh [AA-BB-CC-DD-EE-FF]", "AA-BB-CC-DD-EE-FF", true},
+		{"ssh [aa:bb:cc:dd:ee:ff]", "AA-BB-CC-DD-EE-FF", true},
+		{"web [x] [AA-BB-CC-DD-EE-01]", "AA-BB-CC-DD-EE-01", true},
+		{"ssh", "", false},
+		{"ssh [not a mac]", "", false},
+		{"[AA-BB-CC-DD-EE-FF] ssh", "", false},
+	}
+
+	for _, test := range tests {
+		mac, ok := PortForwardClientMac(PortForward{Name: test.name})
+		if mac != test.mac || ok != test.ok {
+			t.Errorf("%q: got %q %v, want %q %v", test.name, mac, ok, test.mac, test.ok)
+		}
+	}
+
+}
+
+func TestRefreshPortForwards(t *testing.T) {
+
+	rules := []PortForward{
+		{Id: "1", Name: "ssh [AA-BB-CC-00-00-01]", ForwardIp: "10.0.0.5", ExternalPort: "22", ForwardPort: "22"},
+		{Id: "2", Name: "web", ForwardIp: "10.0.0.6", ExternalPort: "22", ForwardPort: "22"},
+		{Id: "3", Name: "game [AA-BB-CC-00-00-03]", ForwardIp: "10.0.0.7", ExternalPort: "22", ForwardPort: "22"},
+		{Id: "4", Name: "current [AA-BB-CC-00-00-04]", ForwardIp: "10.0.0.8", ExternalPort: "22", ForwardPort: "22"},
+	}
+	clients := []Client{
+		{Name: "nas", Ip: "10.0.0.50", MAC: "AA-BB-CC-00-00-01"},
+		{Name: "web", Ip: "10.0.0.60", MAC: "AA-BB-CC-00-00-02"},
+		{Name: "pc", Ip: "10.0.0.8", MAC: "AA-BB-CC-00-00-04"},
+	}
+
+	var patched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		switch path := r.URL.Path; {
+		case r.Method == "PATCH":
+			patched = append(patched, path[strings.LastIndex(path, "/")+1:])
+		case strings.HasSuffix(path, "/setting/lan/networks"):
+			result = map[string]interface{}{"data": []OmadaNetwork{{Subnet: "10.0.0.1/24"}}}
+		case strings.HasSuffix(path, "/portForwardings"):
+			result = map[string]interface{}{"data": rules}
+		case strings.HasSuffix(path, "/clients"):
+			result = map[string]interface{}{"data": clients}
+		case strings.HasSuffix(path, "/devices"):
+			result = []Device{}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "site"}
+	updated, err := c.RefreshPortForwards(PortForwardBinding{RuleId: "2", Mac: "aa:bb:cc:00:00:02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(patched, " ") != "1 2" || len(updated) != 2 || updated[0].ForwardIp != "10.0.0.50" || updated[1].ForwardIp != "10.0.0.60" {
+		t.Errorf("got patched %v updated %+v", patched, updated)
+	}
+
+}
@@ -0,0 +1,185 @@
+package omada
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	Dot1xForceUnauthorized = 0
+	Dot1xForceAuthorized   = 1
+	Dot1xAuto              = 2
+)
+
+const (
+	BandwidthControlOff       = 0
+	BandwidthControlRateLimit = 1
+	BandwidthControlStorm     = 2
+)
+
+type PortProfile struct {
+	Id                   string         `json:"id,omitempty"`
+	Name                 string         `json:"name"`
+	Type                 int            `json:"type,omitempty"`
+	NativeNetworkId      string         `json:"nativeNetworkId"`
+	TagNetworkIds        []string       `json:"tagNetworkIds"`
+	UntagNetworkIds      []string       `json:"untagNetworkIds"`
+	Poe                  int            `json:"poe"`
+	Dot1x                int            `json:"dot1x"`
+	PortIsolationEnable  bool           `json:"portIsolationEnable"`
+	LldpMedEnable        bool           `json:"lldpMedEnable"`
+	TopoNotifyEnable     bool           `json:"topoNotifyEnable"`
+	SpanningTreeEnable   bool           `json:"spanningTreeEnable"`
+	LoopbackDetectEnable bool           `json:"loopbackDetectEnable"`
+	EeeEnable            bool           `json:"eeeEnable"`
+	FlowControlEnable    bool           `json:"flowControlEnable"`
+	BandWidthCtrlType    int            `json:"bandWidthCtrlType"`
+	EgressRateLimit      PortRateLimit  `json:"egressRateLimit"`
+	IngressRateLimit     PortRateLimit  `json:"ingressRateLimit"`
+	StormCtrl            PortStormCtrl  `json:"stormCtrl"`
+	DhcpL2RelaySettings  *PortDhcpRelay `json:"dhcpL2RelaySettings,omitempty"`
+}
+
+type PortRateLimit struct {
+	Enable   bool `json:"enable"`
+	Rate     int  `json:"rate"`
+	RateUnit int  `json:"rateUnit"`
+}
+
+type PortStormCtrl struct {
+	RateMode             int  `json:"rateMode"`
+	UnknownUnicastEnable bool `json:"unknownUnicastEnable"`
+	UnknownUnicast       int  `json:"unknownUnicast"`
+	MulticastEnable      bool `json:"multicastEnable"`
+	Multicast            int  `json:"multicast"`
+	BroadcastEnable      bool `json:"broadcastEnable"`
+	Broadcast            int  `json:"broadcast"`
+	Action               int  `json:"action"`
+	RecoverTime          int  `json:"recoverTime"`
+}
+
+type PortDhcpRelay struct {
+	Enable bool `json:"enable"`
+	Format int  `json:"format"`
+}
+
+func (c *Controller) GetPortProfiles() ([]PortProfile, error) {
+
+	var profiles []PortProfile
+	if err := c.getPaged(c.siteId, "setting/lan/profiles", &profiles); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+
+}
+
+func (c *Controller) GetPortProfile(id string) (PortProfile, error) {
+
+	profiles, err := c.GetPortProfiles()
+	if err != nil {
+		return PortProfile{}, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Id == id {
+			return profile, nil
+		}
+	}
+
+	return PortProfile{}, fmt.Errorf("port profile not found: %s", id)
+
+}
+
+func (c *Controller) CreatePortProfile(profile PortProfile) (PortProfile, error) {
+
+	if err := c.checkPortProfileNetworks(profile); err != nil {
+		return PortProfile{}, err
+	}
+
+	profile.Id = ""
+	url := c.siteURL(c.siteId, "setting/lan/profiles")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, profile, &created); err != nil {
+		return PortProfile{}, err
+	}
+
+	profile.Id = created.Id
+	return profile, nil
+
+}
+
+func (c *Controller) UpdatePortProfile(profile PortProfile) error {
+
+	if profile.Id == "" {
+		return fmt.Errorf("port profile id is required")
+	}
+
+	if err := c.checkPortProfileNetworks(profile); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/lan/profiles/"+profile.Id)
+	return c.doRequest("PATCH", url, profile, nil)
+
+}
+
+func (c *Controller) DeletePortProfile(id string) error {
+
+	devices, err := c.getSiteDevices(c.siteId)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if device.Type != "switch" {
+			continue
+		}
+		ports, err := c.GetSwitchPorts(device.Mac)
+		if err != nil {
+			return err
+		}
+		for _, port := range ports {
+			if port.ProfileId == id {
+				return fmt.Errorf("port profile %s is still assigned to %s port %d", id, device.Name, port.Port)
+			}
+		}
+	}
+
+	url := c.siteURL(c.siteId, "setting/lan/profiles/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) checkPortProfileNetworks(profile PortProfile) error {
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	for _, network := range networks {
+		known[network.Id] = true
+	}
+
+	ids := append([]string{}, profile.TagNetworkIds...)
+	ids = append(ids, profile.UntagNetworkIds...)
+	if profile.NativeNetworkId != "" {
+		ids = append(ids, profile.NativeNetworkId)
+	}
+
+	for _, id := range ids {
+		if !known[id] {
+			return fmt.Errorf("port profile %s refers to unknown network: %s", profile.Name, id)
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,184 @@
+package omada
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+const (
+	HostKindClient = "client"
+	HostKindDevice = "device"
+)
+
+func (n OmadaNetwork) Prefix() (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(n.Subnet)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+func (n OmadaNetwork) GatewayAddr() (netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(n.Subnet)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return prefix.Addr(), nil
+}
+
+func (c Client) Addr() (netip.Addr, error) {
+	return netip.ParseAddr(c.Ip)
+}
+
+func (d Device) Addr() (netip.Addr, error) {
+	return netip.ParseAddr(d.IP)
+}
+
+// HostNetwork is a client or device paired with the network its ip falls in.
+// Matched is false when the ip could not be parsed or fits no known network.
+type HostNetwork struct {
+	Kind      string
+	Name      string
+	DnsName   string
+	Mac       string
+	Ip        netip.Addr
+	Matched   bool
+	NetworkId string
+	Network   string
+	Vlan      int
+	Domain    string
+}
+
+func (h HostNetwork) Fqdn() string {
+	if h.Domain == "" {
+		return h.DnsName
+	}
+	return h.DnsName + "." + strings.TrimSuffix(h.Domain, ".")
+}
+
+type resolverEntry struct {
+	prefix  netip.Prefix
+	network OmadaNetwork
+}
+
+type NetworkResolver struct {
+	entries []resolverEntry
+}
+
+func NewNetworkResolver(networks []OmadaNetwork) *NetworkResolver {
+
+	var entries []resolverEntry
+	for _, network := range networks {
+		prefix, err := network.Prefix()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, resolverEntry{prefix: prefix, network: network})
+	}
+
+	// longest prefix first so the most specific network wins
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].prefix.Bits() > entries[j].prefix.Bits()
+	})
+
+	return &NetworkResolver{entries: entries}
+}
+
+func (r *NetworkResolver) Lookup(addr netip.Addr) (OmadaNetwork, bool) {
+
+	addr = addr.Unmap()
+	for _, entry := range r.entries {
+		if entry.prefix.Contains(addr) {
+			return entry.network, true
+		}
+	}
+
+	return OmadaNetwork{}, false
+
+}
+
+func (r *NetworkResolver) ResolveClients(clients []Client) []HostNetwork {
+
+	var hosts []HostNetwork
+	for _, client := range clients {
+		hosts = append(hosts, r.resolve(HostKindClient, client.Name, client.DnsName, client.MAC, client.Ip))
+	}
+
+	return hosts
+
+}
+
+func (r *NetworkResolver) ResolveDevices(devices []Device) []HostNetwork {
+
+	var hosts []HostNetwork
+	for _, device := range devices {
+		hosts = append(hosts, r.resolve(HostKindDevice, device.Name, device.DnsName, device.Mac, device.IP))
+	}
+
+	return hosts
+
+}
+
+func (r *NetworkResolver) resolve(kind string, name string, dnsName string, mac string, ip string) HostNetwork {
+
+	host := HostNetwork{
+		Kind:    kind,
+		Name:    name,
+		DnsName: dnsName,
+		Mac:     mac,
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return host
+	}
+	host.Ip = addr
+
+	network, ok := r.Lookup(addr)
+	if !ok {
+		return host
+	}
+
+	host.Matched = true
+	host.NetworkId = network.Id
+	host.Network = network.Name
+	host.Vlan = network.Vlan
+	host.Domain = network.Domain
+
+	return host
+
+}
+
+func (c *Controller) ResolveHostNetworks() ([]HostNetwork, error) {
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	clients, devices, err := c.GetHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := NewNetworkResolver(networks)
+	hosts := resolver.ResolveClients(clients)
+	hosts = append(hosts, resolver.ResolveDevices(devices)...)
+
+	return hosts, nil
+
+}
+
+func UnmatchedHosts(hosts []HostNetwork) []HostNetwork {
+
+	var unmatched []HostNetwork
+	for _, host := range hosts {
+		if !host.Matched {
+			unmatched = append(unmatched, host)
+		}
+	}
+
+	return unmatched
+
+}
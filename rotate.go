@@ -0,0 +1,268 @@
+package omada
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// passphraseChars leaves out characters that are easy to misread on signage
+// such as 0/O and 1/l/I.
+const passphraseChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+type RotationOptions struct {
+	SsidName   string
+	SiteIds    []string
+	Passphrase string
+	Length     int
+	Words      int
+	Separator  string
+}
+
+type RotatedSsid struct {
+	SiteId string
+	WlanId string
+	SsidId string
+	Hidden bool
+}
+
+type RotationResult struct {
+	SsidName   string
+	Passphrase string
+	Updated    []RotatedSsid
+}
+
+func GeneratePassphrase(length int) (string, error) {
+
+	if length < 8 || length > 63 {
+		return "", fmt.Errorf("passphrase length must be between 8 and 63, got: %d", length)
+	}
+
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := randomInt(len(passphraseChars))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(passphraseChars[n])
+	}
+
+	return sb.String(), nil
+
+}
+
+func GenerateWordPassphrase(words int, separator string) (string, error) {
+
+	if words < 2 {
+		return "", fmt.Errorf("a word passphrase needs at least 2 words, got: %d", words)
+	}
+
+	var parts []string
+	for i := 0; i < words; i++ {
+		n, err := randomInt(len(passphraseWords))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, passphraseWords[n])
+	}
+
+	n, err := randomInt(100)
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, fmt.Sprintf("%02d", n))
+
+	passphrase := strings.Join(parts, separator)
+	if err := validatePassphrase(passphrase); err != nil {
+		return "", err
+	}
+
+	return passphrase, nil
+
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+func (o RotationOptions) passphrase() (string, error) {
+
+	if o.Passphrase != "" {
+		return o.Passphrase, validatePassphrase(o.Passphrase)
+	}
+
+	if o.Words > 0 {
+		separator := o.Separator
+		if separator == "" {
+			separator = "-"
+		}
+		return GenerateWordPassphrase(o.Words, separator)
+	}
+
+	length := o.Length
+	if length == 0 {
+		length = 16
+	}
+	return GeneratePassphrase(length)
+
+}
+
+// RotateSsidPassphrase sets a new passphrase on every ssid called
+// opts.SsidName in the chosen sites and reads each one back to confirm the
+// change. With no SiteIds every site the user can access is rotated.
+func (c *Controller) RotateSsidPassphrase(opts RotationOptions) (RotationResult, error) {
+
+	if opts.SsidName == "" {
+		return RotationResult{}, fmt.Errorf("ssid name is required")
+	}
+
+	siteIds := opts.SiteIds
+	if len(siteIds) == 0 {
+		siteIds = c.allSiteIds
+	}
+	for _, siteId := range siteIds {
+		if !contains(c.allSiteIds, siteId) {
+			return RotationResult{}, fmt.Errorf("site not found: %s", siteId)
+		}
+	}
+
+	passphrase, err := opts.passphrase()
+	if err != nil {
+		return RotationResult{}, err
+	}
+
+	result := RotationResult{
+		SsidName:   opts.SsidName,
+		Passphrase: passphrase,
+	}
+
+	for _, siteId := range siteIds {
+		rotated, err := c.rotateSitePassphrase(siteId, opts.SsidName, passphrase)
+		if err != nil {
+			return result, fmt.Errorf("site %s: %w", siteId, err)
+		}
+		result.Updated = append(result.Updated, rotated...)
+	}
+
+	if len(result.Updated) == 0 {
+		return result, fmt.Errorf("ssid not found: %s", opts.SsidName)
+	}
+
+	return result, nil
+
+}
+
+func (c *Controller) rotateSitePassphrase(siteId string, ssidName string, passphrase string) ([]RotatedSsid, error) {
+
+	groups, err := c.getWlanGroups(siteId)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotated []RotatedSsid
+	for _, group := range groups {
+		ssids, err := c.getSsids(siteId, group.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ssid := range ssids {
+			if ssid.Name != ssidName {
+				continue
+			}
+			if ssid.Security != SsidSecurityWpaPersonal || ssid.PskSetting == nil {
+				return nil, fmt.Errorf("ssid %s is not using wpa personal", ssid.Name)
+			}
+
+			ssid.PskSetting.SecurityKey = passphrase
+			if err := c.updateSsid(siteId, group.Id, ssid); err != nil {
+				return nil, err
+			}
+			if err := c.verifySsidPassphrase(siteId, group.Id, ssid.Id, passphrase); err != nil {
+				return nil, err
+			}
+
+			rotated = append(rotated, RotatedSsid{
+				SiteId: siteId,
+				WlanId: group.Id,
+				SsidId: ssid.Id,
+				Hidden: ssid.Hidden(),
+			})
+		}
+	}
+
+	return rotated, nil
+
+}
+
+func (c *Controller) verifySsidPassphrase(siteId string, wlanId string, ssidId string, passphrase string) error {
+
+	ssids, err := c.getSsids(siteId, wlanId)
+	if err != nil {
+		return err
+	}
+
+	for _, ssid := range ssids {
+		if ssid.Id != ssidId {
+			continue
+		}
+		if ssid.PskSetting == nil || ssid.PskSetting.SecurityKey != passphrase {
+			return fmt.Errorf("ssid %s: passphrase was not applied", ssid.Name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("ssid not found after update: %s", ssidId)
+
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// WifiQRPayload builds the WIFI: string understood by phone cameras.
+func WifiQRPayload(ssid string, passphrase string, hidden bool) string {
+
+	payload := fmt.Sprintf("WIFI:T:WPA;S:%s;P:%s;", escapeWifiQR(ssid), escapeWifiQR(passphrase))
+	if hidden {
+		payload += "H:true;"
+	}
+
+	return payload + ";"
+
+}
+
+func escapeWifiQR(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+	return replacer.Replace(value)
+}
+
+func WifiQRPNG(ssid string, passphrase string, hidden bool, size int) ([]byte, error) {
+
+	return qrcode.Encode(WifiQRPayload(ssid, passphrase, hidden), qrcode.Medium, size)
+
+}
+
+func (r RotationResult) QRPayload() string {
+	return WifiQRPayload(r.SsidName, r.Passphrase, r.hidden())
+}
+
+func (r RotationResult) QRPNG(size int) ([]byte, error) {
+	return WifiQRPNG(r.SsidName, r.Passphrase, r.hidden(), size)
+}
+
+func (r RotationResult) hidden() bool {
+	return len(r.Updated) > 0 && r.Updated[0].Hidden
+}
@@ -0,0 +1,270 @@
+package omada
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+const (
+	RouteTypeNextHop   = 0
+	RouteTypeInterface = 1
+)
+
+const (
+	RouteTargetNetwork = 0
+	RouteTargetIpGroup = 1
+)
+
+type StaticRoute struct {
+	Id           string         `json:"id,omitempty"`
+	Name         string         `json:"name"`
+	Status       bool           `json:"status"`
+	Destinations []netip.Prefix `json:"destinations"`
+	RouteType    int            `json:"routeType"`
+	NextHop      netip.Addr     `json:"nextHopIp"`
+	InterfaceId  string         `json:"interfaceId,omitempty"`
+	Metric       int            `json:"metric"`
+}
+
+type PolicyRoute struct {
+	Id              string   `json:"id,omitempty"`
+	Name            string   `json:"name"`
+	Status          bool     `json:"status"`
+	Protocols       []int    `json:"protocols"`
+	SourceType      int      `json:"sourceType"`
+	SourceIds       []string `json:"sourceIds"`
+	DestinationType int      `json:"destinationType"`
+	DestinationIds  []string `json:"destinationIds"`
+	WanPortIds      []string `json:"wanPortIds"`
+	BackupInterface bool     `json:"backupInterface"`
+}
+
+func (r StaticRoute) Validate() error {
+
+	if r.Name == "" {
+		return fmt.Errorf("static route name is required")
+	}
+
+	if len(r.Destinations) == 0 {
+		return fmt.Errorf("static route %s: at least one destination is required", r.Name)
+	}
+	for _, destination := range r.Destinations {
+		if !destination.IsValid() {
+			return fmt.Errorf("static route %s: invalid destination", r.Name)
+		}
+	}
+
+	switch r.RouteType {
+	case RouteTypeNextHop:
+		if !r.NextHop.IsValid() {
+			return fmt.Errorf("static route %s: next hop is required", r.Name)
+		}
+	case RouteTypeInterface:
+		if r.InterfaceId == "" {
+			return fmt.Errorf("static route %s: interface is required", r.Name)
+		}
+	default:
+		return fmt.Errorf("static route %s: unknown route type: %d", r.Name, r.RouteType)
+	}
+
+	if r.Metric < 0 || r.Metric > 255 {
+		return fmt.Errorf("static route %s: metric must be between 0 and 255, got: %d", r.Name, r.Metric)
+	}
+
+	return nil
+
+}
+
+func (r PolicyRoute) Validate() error {
+
+	if r.Name == "" {
+		return fmt.Errorf("policy route name is required")
+	}
+
+	for _, target := range []int{r.SourceType, r.DestinationType} {
+		if target != RouteTargetNetwork && target != RouteTargetIpGroup {
+			return fmt.Errorf("policy route %s: unknown source or destination type: %d", r.Name, target)
+		}
+	}
+
+	if len(r.SourceIds) == 0 {
+		return fmt.Errorf("policy route %s: at least one source is required", r.Name)
+	}
+
+	if len(r.WanPortIds) == 0 {
+		return fmt.Errorf("policy route %s: at least one wan port is required", r.Name)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetStaticRoutes() ([]StaticRoute, error) {
+
+	var routes []StaticRoute
+	if err := c.getPaged(c.siteId, "setting/transmission/staticRoutings", &routes); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Name < routes[j].Name
+	})
+
+	return routes, nil
+
+}
+
+func (c *Controller) GetStaticRoute(id string) (StaticRoute, error) {
+
+	routes, err := c.GetStaticRoutes()
+	if err != nil {
+		return StaticRoute{}, err
+	}
+
+	for _, route := range routes {
+		if route.Id == id {
+			return route, nil
+		}
+	}
+
+	return StaticRoute{}, fmt.Errorf("static route not found: %s", id)
+
+}
+
+func (c *Controller) CreateStaticRoute(route StaticRoute) (StaticRoute, error) {
+
+	route.Id = ""
+	if err := c.checkStaticRoute(route); err != nil {
+		return StaticRoute{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/staticRoutings")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, route, &created); err != nil {
+		return StaticRoute{}, err
+	}
+
+	route.Id = created.Id
+	return route, nil
+
+}
+
+func (c *Controller) UpdateStaticRoute(route StaticRoute) error {
+
+	if route.Id == "" {
+		return fmt.Errorf("static route id is required")
+	}
+
+	if err := c.checkStaticRoute(route); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/staticRoutings/"+route.Id)
+	return c.doRequest("PATCH", url, route, nil)
+
+}
+
+func (c *Controller) DeleteStaticRoute(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/transmission/staticRoutings/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) checkStaticRoute(route StaticRoute) error {
+
+	if err := route.Validate(); err != nil {
+		return err
+	}
+
+	if route.RouteType != RouteTypeNextHop {
+		return nil
+	}
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := NewNetworkResolver(networks).Lookup(route.NextHop); !ok {
+		return fmt.Errorf("static route %s: next hop %s is not within any lan network", route.Name, route.NextHop)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetPolicyRoutes() ([]PolicyRoute, error) {
+
+	var routes []PolicyRoute
+	if err := c.getPaged(c.siteId, "setting/transmission/policyRoutings", &routes); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Name < routes[j].Name
+	})
+
+	return routes, nil
+
+}
+
+func (c *Controller) GetPolicyRoute(id string) (PolicyRoute, error) {
+
+	routes, err := c.GetPolicyRoutes()
+	if err != nil {
+		return PolicyRoute{}, err
+	}
+
+	for _, route := range routes {
+		if route.Id == id {
+			return route, nil
+		}
+	}
+
+	return PolicyRoute{}, fmt.Errorf("policy route not found: %s", id)
+
+}
+
+func (c *Controller) CreatePolicyRoute(route PolicyRoute) (PolicyRoute, error) {
+
+	route.Id = ""
+	if err := route.Validate(); err != nil {
+		return PolicyRoute{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/policyRoutings")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, route, &created); err != nil {
+		return PolicyRoute{}, err
+	}
+
+	route.Id = created.Id
+	return route, nil
+
+}
+
+func (c *Controller) UpdatePolicyRoute(route PolicyRoute) error {
+
+	if route.Id == "" {
+		return fmt.Errorf("policy route id is required")
+	}
+
+	if err := route.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/transmission/policyRoutings/"+route.Id)
+	return c.doRequest("PATCH", url, route, nil)
+
+}
+
+func (c *Controller) DeletePolicyRoute(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/transmission/policyRoutings/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
@@ -0,0 +1,314 @@
+package omada
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// StaticDnsSyncOwner is the description SyncStaticDns gives the entries it
+// creates. Entries without it are never changed by the sync.
+const StaticDnsSyncOwner = "managed by go-omada sync"
+
+// StaticDns is a host entry answered by the gateway's lan dns resolver. Only
+// newer gateways support these, older ones return an omada api error.
+type StaticDns struct {
+	Id          string `json:"id,omitempty"`
+	Status      bool   `json:"status"`
+	Hostname    string `json:"domainName"`
+	Ip          string `json:"ip"`
+	Description string `json:"description,omitempty"`
+}
+
+// StaticDnsSyncOptions controls how SyncStaticDns maps clients to entries.
+type StaticDnsSyncOptions struct {
+	// Domain is appended to clients whose network has no domain set. Clients
+	// with neither get an entry for their bare dns name.
+	Domain string
+
+	// Prune deletes enabled entries the sync created that no client claims
+	// anymore.
+	Prune bool
+
+	// DryRun reports the changes without writing them.
+	DryRun bool
+}
+
+type StaticDnsChanges struct {
+	Created []StaticDns
+	Updated []StaticDns
+	Deleted []StaticDns
+}
+
+func (c StaticDnsChanges) Empty() bool {
+	return len(c.Created)+len(c.Updated)+len(c.Deleted) == 0
+}
+
+func (s StaticDns) Validate() error {
+
+	if s.Hostname == "" {
+		return fmt.Errorf("static dns hostname is required")
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(s.Hostname, "."), ".") {
+		if !validLabel(strings.ToLower(label)) {
+			return fmt.Errorf("static dns %s: invalid hostname label: %q", s.Hostname, label)
+		}
+	}
+
+	if _, err := netip.ParseAddr(s.Ip); err != nil {
+		return fmt.Errorf("static dns %s: invalid ip: %s", s.Hostname, s.Ip)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetStaticDns() ([]StaticDns, error) {
+
+	var entries []StaticDns
+	if err := c.getPaged(c.siteId, "setting/service/dns/static", &entries); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Hostname != entries[j].Hostname {
+			return entries[i].Hostname < entries[j].Hostname
+		}
+		return entries[i].Ip < entries[j].Ip
+	})
+
+	return entries, nil
+
+}
+
+func (c *Controller) GetStaticDnsEntry(id string) (StaticDns, error) {
+
+	entries, err := c.GetStaticDns()
+	if err != nil {
+		return StaticDns{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.Id == id {
+			return entry, nil
+		}
+	}
+
+	return StaticDns{}, fmt.Errorf("static dns entry not found: %s", id)
+
+}
+
+func (c *Controller) CreateStaticDns(entry StaticDns) (StaticDns, error) {
+
+	entry.Id = ""
+	if err := entry.Validate(); err != nil {
+		return StaticDns{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/service/dns/static")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, entry, &created); err != nil {
+		return StaticDns{}, err
+	}
+
+	entry.Id = created.Id
+	return entry, nil
+
+}
+
+func (c *Controller) UpdateStaticDns(entry StaticDns) error {
+
+	if entry.Id == "" {
+		return fmt.Errorf("static dns id is required")
+	}
+
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/service/dns/static/"+entry.Id)
+	return c.doRequest("PATCH", url, entry, nil)
+
+}
+
+func (c *Controller) SetStaticDnsEnabled(id string, enabled bool) error {
+
+	entry, err := c.GetStaticDnsEntry(id)
+	if err != nil {
+		return err
+	}
+
+	entry.Status = enabled
+	return c.UpdateStaticDns(entry)
+
+}
+
+func (c *Controller) DeleteStaticDns(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/service/dns/static/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+// SyncStaticDns keeps one entry per connected client, named after its dns
+// name and network domain and pointed at its current ip. Only entries
+// described as StaticDnsSyncOwner are changed, a name that already has an
+// entry added by hand or by another tool is left to it. Disabled entries
+// are never touched either, disabling an entry pins it or opts a client out.
+func (c *Controller) SyncStaticDns(opts StaticDnsSyncOptions) (StaticDnsChanges, error) {
+
+	var changes StaticDnsChanges
+
+	existing, err := c.GetStaticDns()
+	if err != nil {
+		return changes, err
+	}
+
+	desired, err := c.desiredStaticDns(opts.Domain)
+	if err != nil {
+		return changes, err
+	}
+
+	byName := make(map[string][]StaticDns)
+	for _, entry := range existing {
+		name := staticDnsName(entry.Hostname)
+		byName[name] = append(byName[name], entry)
+	}
+
+	for _, want := range desired {
+		entries := byName[staticDnsName(want.Hostname)]
+
+		var enabled []StaticDns
+		skip := false
+		for _, entry := range entries {
+			if !entry.Status || entry.Description != StaticDnsSyncOwner {
+				skip = true
+				continue
+			}
+			enabled = append(enabled, entry)
+		}
+		if skip {
+			continue
+		}
+
+		if len(enabled) == 0 {
+			if !opts.DryRun {
+				want, err = c.CreateStaticDns(want)
+				if err != nil {
+					return changes, err
+				}
+			}
+			changes.Created = append(changes.Created, want)
+			continue
+		}
+
+		// keep the entry already pointing at the ip, or else the first one,
+		// and drop any duplicates for the same name
+		sort.SliceStable(enabled, func(i, j int) bool {
+			return enabled[i].Ip == want.Ip && enabled[j].Ip != want.Ip
+		})
+		entry := enabled[0]
+		if entry.Ip != want.Ip {
+			entry.Ip = want.Ip
+			if !opts.DryRun {
+				if err := c.UpdateStaticDns(entry); err != nil {
+					return changes, err
+				}
+			}
+			changes.Updated = append(changes.Updated, entry)
+		}
+		for _, duplicate := range enabled[1:] {
+			if err := c.deleteStaticDns(duplicate, opts.DryRun, &changes); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	if !opts.Prune {
+		return changes, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, want := range desired {
+		wanted[staticDnsName(want.Hostname)] = true
+	}
+	for _, entry := range existing {
+		if !entry.Status || entry.Description != StaticDnsSyncOwner || wanted[staticDnsName(entry.Hostname)] {
+			continue
+		}
+		if err := c.deleteStaticDns(entry, opts.DryRun, &changes); err != nil {
+			return changes, err
+		}
+	}
+
+	return changes, nil
+
+}
+
+func (c *Controller) deleteStaticDns(entry StaticDns, dryRun bool, changes *StaticDnsChanges) error {
+
+	if !dryRun {
+		if err := c.DeleteStaticDns(entry.Id); err != nil {
+			return err
+		}
+	}
+	changes.Deleted = append(changes.Deleted, entry)
+
+	return nil
+
+}
+
+// desiredStaticDns returns an enabled entry for every client with a dns name
+// and ip.
+func (c *Controller) desiredStaticDns(domain string) ([]StaticDns, error) {
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := c.GetClients()
+	if err != nil {
+		return nil, err
+	}
+
+	domain = staticDnsName(domain)
+
+	seen := make(map[string]bool)
+	var desired []StaticDns
+	for _, host := range NewNetworkResolver(networks).ResolveClients(clients) {
+		if host.DnsName == "" || !host.Ip.IsValid() {
+			continue
+		}
+
+		if host.Domain == "" {
+			host.Domain = domain
+		}
+		hostname := staticDnsName(host.Fqdn())
+		if seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+
+		desired = append(desired, StaticDns{
+			Status:      true,
+			Hostname:    hostname,
+			Ip:          host.Ip.Unmap().String(),
+			Description: StaticDnsSyncOwner,
+		})
+	}
+
+	sort.Slice(desired, func(i, j int) bool {
+		return desired[i].Hostname < desired[j].Hostname
+	})
+
+	return desired, nil
+
+}
+
+func staticDnsName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,72 @@
+package omada
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSyncStaticDnsOwnership(t *testing.T) {
+
+	entries := []StaticDns{
+		{Id: "1", Status: true, Hostname: "nas.home.lan", Ip: "10.0.0.9", Description: StaticDnsSyncOwner},
+		{Id: "2", Status: true, Hostname: "printer.home.lan", Ip: "10.0.0.6"},
+		{Id: "3", Status: true, Hostname: "old.home.lan", Ip: "10.0.0.20", Description: StaticDnsSyncOwner},
+		{Id: "4", Status: true, Hostname: "manual.home.lan", Ip: "10.0.0.21"},
+		{Id: "5", Status: true, Hostname: "app.home.lan", Ip: "10.0.0.22", Description: "managed by go-omada external-dns"},
+		{Id: "6", Status: false, Hostname: "tv.home.lan", Ip: "10.0.0.23", Description: StaticDnsSyncOwner},
+	}
+	clients := []Client{
+		{Name: "nas", Ip: "10.0.0.5", MAC: "AA-BB-CC-00-00-01"},
+		{Name: "printer", Ip: "10.0.0.7", MAC: "AA-BB-CC-00-00-02"},
+		{Name: "laptop", Ip: "10.0.0.8", MAC: "AA-BB-CC-00-00-03"},
+		{Name: "tv", Ip: "10.0.0.10", MAC: "AA-BB-CC-00-00-04"},
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, "/setting/lan/networks"):
+			result = map[string]interface{}{"data": []OmadaNetwork{{Domain: "home.lan", Subnet: "10.0.0.1/24"}}}
+		case strings.HasSuffix(path, "/clients"):
+			result = map[string]interface{}{"data": clients}
+		case strings.HasSuffix(path, "/devices"):
+			result = []Device{}
+		case strings.HasSuffix(path, "/setting/service/dns/static") && r.Method == "GET":
+			result = map[string]interface{}{"data": entries}
+		default:
+			var entry StaticDns
+			json.NewDecoder(r.Body).Decode(&entry)
+			requests = append(requests, fmt.Sprintf("%s %s %s %s %s", r.Method, path[strings.LastIndex(path, "/")+1:], entry.Hostname, entry.Ip, entry.Description))
+			result = createdResponse{Id: "new"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errorCode": 0, "result": result})
+	}))
+	defer server.Close()
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "site"}
+	changes, err := c.SyncStaticDns(StaticDnsSyncOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(requests)
+	want := []string{
+		"DELETE 3   ",
+		"PATCH 1 nas.home.lan 10.0.0.5 " + StaticDnsSyncOwner,
+		"POST static laptop.home.lan 10.0.0.8 " + StaticDnsSyncOwner,
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got requests\n%s\nwant\n%s", strings.Join(requests, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(changes.Created) != 1 || len(changes.Updated) != 1 || len(changes.Deleted) != 1 {
+		t.Errorf("got changes %+v", changes)
+	}
+
+}
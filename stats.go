@@ -0,0 +1,240 @@
+package omada
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Granularity string
+
+const (
+	Granularity5Min   Granularity = "5min"
+	GranularityHourly Granularity = "hourly"
+	GranularityDaily  Granularity = "daily"
+)
+
+// maxStatsRange is roughly how much history the controller keeps at each
+// granularity, longer ranges are refused instead of silently truncated.
+var maxStatsRange = map[Granularity]time.Duration{
+	Granularity5Min:   24 * time.Hour,
+	GranularityHourly: 7 * 24 * time.Hour,
+	GranularityDaily:  366 * 24 * time.Hour,
+}
+
+type StatsQuery struct {
+	Start       time.Time
+	End         time.Time
+	Granularity Granularity
+}
+
+type TrafficPoint struct {
+	Time time.Time
+	Rx   int64
+	Tx   int64
+}
+
+type TrafficSeries struct {
+	Granularity Granularity
+	Points      []TrafficPoint
+}
+
+type ClientUsage struct {
+	Mac     string `json:"mac"`
+	Name    string `json:"name"`
+	Rx      int64  `json:"download"`
+	Tx      int64  `json:"upload"`
+	Total   int64  `json:"traffic"`
+	DnsName string `json:"-"`
+}
+
+type ApplicationUsage struct {
+	Id       int    `json:"applicationId"`
+	Name     string `json:"applicationName"`
+	Category string `json:"familyName"`
+	Rx       int64  `json:"download"`
+	Tx       int64  `json:"upload"`
+	Total    int64  `json:"traffic"`
+}
+
+type trafficPointWire struct {
+	Time int64 `json:"time"`
+	Rx   int64 `json:"rx"`
+	Tx   int64 `json:"tx"`
+}
+
+func (p *TrafficPoint) UnmarshalJSON(data []byte) error {
+
+	var wire trafficPointWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	*p = TrafficPoint{
+		Time: time.Unix(wire.Time, 0),
+		Rx:   wire.Rx,
+		Tx:   wire.Tx,
+	}
+
+	return nil
+
+}
+
+func (s TrafficSeries) Total() (rx int64, tx int64) {
+	for _, point := range s.Points {
+		rx += point.Rx
+		tx += point.Tx
+	}
+	return rx, tx
+}
+
+func (q StatsQuery) Validate() error {
+
+	if q.Start.IsZero() || q.End.IsZero() {
+		return fmt.Errorf("stats query needs a start and end time")
+	}
+
+	if !q.End.After(q.Start) {
+		return fmt.Errorf("stats query end %s is not after start %s", q.End, q.Start)
+	}
+
+	max, ok := maxStatsRange[q.Granularity]
+	if !ok {
+		return fmt.Errorf("unknown stats granularity: %s", q.Granularity)
+	}
+	if q.End.Sub(q.Start) > max {
+		return fmt.Errorf("stats range %s is longer than the %s allowed for %s granularity", q.End.Sub(q.Start), max, q.Granularity)
+	}
+
+	return nil
+
+}
+
+func (q StatsQuery) params() string {
+	return fmt.Sprintf("start=%d&end=%d&interval=%s", q.Start.Unix(), q.End.Unix(), q.Granularity)
+}
+
+func (c *Controller) getTrafficSeries(path string, query StatsQuery) (TrafficSeries, error) {
+
+	if err := query.Validate(); err != nil {
+		return TrafficSeries{}, err
+	}
+
+	url := c.siteURL(c.siteId, path+"?"+query.params())
+
+	var points []TrafficPoint
+	if err := c.doRequest("GET", url, nil, &points); err != nil {
+		return TrafficSeries{}, err
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return TrafficSeries{Granularity: query.Granularity, Points: points}, nil
+
+}
+
+func (c *Controller) GetSiteTraffic(query StatsQuery) (TrafficSeries, error) {
+	return c.getTrafficSeries("stat/traffic", query)
+}
+
+func (c *Controller) GetDeviceTraffic(mac string, query StatsQuery) (TrafficSeries, error) {
+	return c.getTrafficSeries(fmt.Sprintf("stat/devices/%s/traffic", mac), query)
+}
+
+func (c *Controller) GetClientTraffic(mac string, query StatsQuery) (TrafficSeries, error) {
+	return c.getTrafficSeries(fmt.Sprintf("stat/clients/%s/traffic", mac), query)
+}
+
+func (c *Controller) GetTopClients(query StatsQuery, limit int) ([]ClientUsage, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("dashboard/topClients?%s&limit=%d", query.params(), limit))
+
+	var usage []ClientUsage
+	if err := c.doRequest("GET", url, nil, &usage); err != nil {
+		return nil, err
+	}
+
+	if err := c.nameUsage(usage); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Total > usage[j].Total
+	})
+
+	return usage, nil
+
+}
+
+// nameUsage gives each client the DnsName GetClients uses. Clients that are
+// no longer connected are named around the current clients and devices so
+// they cannot take one of their names.
+func (c *Controller) nameUsage(usage []ClientUsage) error {
+
+	clients, devices, err := c.getSiteHosts(c.siteId)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string)
+	reserved := make(map[string]bool)
+	for _, client := range clients {
+		names[normalizeMac(client.MAC)] = client.DnsName
+		reserved[client.DnsName] = true
+	}
+	for _, device := range devices {
+		reserved[device.DnsName] = true
+	}
+
+	var missing []int
+	var candidates []NameCandidate
+	for i := range usage {
+		if name, ok := names[normalizeMac(usage[i].Mac)]; ok {
+			usage[i].DnsName = name
+			continue
+		}
+		missing = append(missing, i)
+		candidates = append(candidates, NameCandidate{
+			Kind:   HostKindClient,
+			Name:   usage[i].Name,
+			Mac:    usage[i].Mac,
+			SiteId: c.siteId,
+		})
+	}
+
+	labels, _ := c.namer.assign(candidates, reserved)
+	for i, index := range missing {
+		usage[index].DnsName = labels[i]
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetTopApplications(query StatsQuery, limit int) ([]ApplicationUsage, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("dashboard/topApplications?%s&limit=%d", query.params(), limit))
+
+	var usage []ApplicationUsage
+	if err := c.doRequest("GET", url, nil, &usage); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Total > usage[j].Total
+	})
+
+	return usage, nil
+
+}
@@ -0,0 +1,200 @@
+package omada
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	PoeDisabled      = 0
+	PoeEnabled       = 1
+	PoeFollowProfile = 2
+)
+
+const (
+	LinkSpeedAuto  = 0
+	LinkSpeed10M   = 1
+	LinkSpeed100M  = 2
+	LinkSpeed1G    = 3
+	LinkSpeed2500M = 4
+	LinkSpeed10G   = 5
+)
+
+const (
+	DuplexAuto = 0
+	DuplexHalf = 1
+	DuplexFull = 2
+)
+
+type SwitchPort struct {
+	Id                    string           `json:"id"`
+	Port                  int              `json:"port"`
+	Name                  string           `json:"name"`
+	Disable               bool             `json:"disable"`
+	Type                  int              `json:"type"`
+	MaxSpeed              int              `json:"maxSpeed"`
+	ProfileId             string           `json:"profileId"`
+	ProfileName           string           `json:"profileName"`
+	ProfileOverrideEnable bool             `json:"profileOverrideEnable"`
+	NativeNetworkId       string           `json:"nativeNetworkId"`
+	Poe                   int              `json:"poe"`
+	LinkSpeed             int              `json:"linkSpeed"`
+	Duplex                int              `json:"duplex"`
+	LagId                 int              `json:"lagId,omitempty"`
+	IsLag                 bool             `json:"lag,omitempty"`
+	PortStatus            SwitchPortStatus `json:"portStatus"`
+}
+
+type SwitchPortStatus struct {
+	Port          int     `json:"port"`
+	LinkStatus    int     `json:"linkStatus"`
+	LinkSpeed     int     `json:"linkSpeed"`
+	Duplex        int     `json:"duplex"`
+	Poe           bool    `json:"poe"`
+	PoePower      float64 `json:"poePower"`
+	Tx            int64   `json:"tx"`
+	Rx            int64   `json:"rx"`
+	TxErrors      int64   `json:"txErrPkts"`
+	RxErrors      int64   `json:"rxErrPkts"`
+	StpDiscarding bool    `json:"stpDiscarding"`
+	StpStatus     int     `json:"stpStatus"`
+}
+
+func (p SwitchPort) LinkUp() bool {
+	return p.PortStatus.LinkStatus == 1
+}
+
+// SwitchPortSettings is sent as a partial update, so only the non-nil fields
+// are changed on the switch.
+type SwitchPortSettings struct {
+	Name                  *string `json:"name,omitempty"`
+	Disable               *bool   `json:"disable,omitempty"`
+	ProfileId             *string `json:"profileId,omitempty"`
+	ProfileOverrideEnable *bool   `json:"profileOverrideEnable,omitempty"`
+	Poe                   *int    `json:"poe,omitempty"`
+	LinkSpeed             *int    `json:"linkSpeed,omitempty"`
+	Duplex                *int    `json:"duplex,omitempty"`
+}
+
+type switchLag struct {
+	LagId int   `json:"lagId"`
+	Ports []int `json:"ports"`
+}
+
+type switchPowerCycle struct {
+	PortList []int `json:"portList"`
+}
+
+func (c *Controller) GetSwitchPorts(mac string) ([]SwitchPort, error) {
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("switches/%s/ports", mac))
+
+	var ports []SwitchPort
+	if err := c.doRequest("GET", url, nil, &ports); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Port < ports[j].Port
+	})
+
+	return ports, nil
+
+}
+
+func (c *Controller) GetSwitchPort(mac string, port int) (SwitchPort, error) {
+
+	ports, err := c.GetSwitchPorts(mac)
+	if err != nil {
+		return SwitchPort{}, err
+	}
+
+	for _, p := range ports {
+		if p.Port == port {
+			return p, nil
+		}
+	}
+
+	return SwitchPort{}, fmt.Errorf("switch port not found: %s/%d", mac, port)
+
+}
+
+func (c *Controller) UpdateSwitchPort(mac string, port int, settings SwitchPortSettings) error {
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("switches/%s/ports/%d", mac, port))
+	return c.doRequest("PATCH", url, settings, nil)
+
+}
+
+func (c *Controller) SetSwitchPortProfile(mac string, port int, profileId string) error {
+	return c.UpdateSwitchPort(mac, port, SwitchPortSettings{ProfileId: &profileId})
+}
+
+func (c *Controller) SetSwitchPortEnabled(mac string, port int, enabled bool) error {
+	disable := !enabled
+	return c.UpdateSwitchPort(mac, port, SwitchPortSettings{Disable: &disable})
+}
+
+func (c *Controller) SetSwitchPortName(mac string, port int, name string) error {
+	return c.UpdateSwitchPort(mac, port, SwitchPortSettings{Name: &name})
+}
+
+func (c *Controller) SetSwitchPortPoe(mac string, port int, enabled bool) error {
+	poe := PoeDisabled
+	if enabled {
+		poe = PoeEnabled
+	}
+	override := true
+	return c.UpdateSwitchPort(mac, port, SwitchPortSettings{Poe: &poe, ProfileOverrideEnable: &override})
+}
+
+func (c *Controller) PowerCycleSwitchPort(mac string, port int) error {
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("cmd/switches/%s/powerCycle", mac))
+	return c.doRequest("POST", url, switchPowerCycle{PortList: []int{port}}, nil)
+
+}
+
+func (c *Controller) SetSwitchLag(mac string, lagId int, ports []int) error {
+
+	if len(ports) < 2 {
+		return fmt.Errorf("a lag needs at least 2 ports, got: %d", len(ports))
+	}
+
+	if err := c.checkLagSupport(mac); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("switches/%s/lags", mac))
+	return c.doRequest("POST", url, switchLag{LagId: lagId, Ports: ports}, nil)
+
+}
+
+func (c *Controller) DeleteSwitchLag(mac string, lagId int) error {
+
+	if err := c.checkLagSupport(mac); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("switches/%s/lags/%d", mac, lagId))
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) checkLagSupport(mac string) error {
+
+	device, err := c.findDevice(mac)
+	if err != nil {
+		return err
+	}
+
+	if device.Type != "switch" {
+		return fmt.Errorf("device is not a switch: %s", mac)
+	}
+	if !device.DeviceMisc.SupportLag {
+		return fmt.Errorf("switch does not support lag: %s", mac)
+	}
+
+	return nil
+
+}
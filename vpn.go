@@ -0,0 +1,332 @@
+package omada
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+const (
+	VpnServerOpenVpn = 1
+	VpnServerL2tp    = 2
+	VpnServerPptp    = 3
+)
+
+const (
+	IkeVersion1 = 1
+	IkeVersion2 = 2
+)
+
+type SiteToSiteVpn struct {
+	Id              string   `json:"id,omitempty"`
+	Name            string   `json:"name"`
+	Status          bool     `json:"status"`
+	RemoteGateway   string   `json:"remoteGateway"`
+	RemoteSubnets   []string `json:"remoteSubnet"`
+	LocalNetworkIds []string `json:"localNetworkIds"`
+	WanPortId       string   `json:"wanPortId"`
+	PreSharedKey    string   `json:"preSharedKey"`
+	IkeVersion      int      `json:"ikeVersion"`
+	Encryption      string   `json:"encryption,omitempty"`
+	Authentication  string   `json:"authentication,omitempty"`
+	DhGroup         int      `json:"dhGroup,omitempty"`
+	Pfs             bool     `json:"pfs"`
+}
+
+type VpnServer struct {
+	Id              string         `json:"id,omitempty"`
+	Name            string         `json:"name"`
+	Status          bool           `json:"status"`
+	Type            int            `json:"type"`
+	WanPortId       string         `json:"wanPortId"`
+	ClientIpPool    string         `json:"ipPool"`
+	LocalNetworkIds []string       `json:"localNetworkIds,omitempty"`
+	OpenVpn         *OpenVpnConfig `json:"openVpnSetting,omitempty"`
+	L2tp            *L2tpConfig    `json:"l2tpSetting,omitempty"`
+	Pptp            *PptpConfig    `json:"pptpSetting,omitempty"`
+}
+
+type OpenVpnConfig struct {
+	Protocol   string `json:"protocol"`
+	Port       int    `json:"port"`
+	Encryption string `json:"encryption,omitempty"`
+	Mode       int    `json:"mode"`
+}
+
+type L2tpConfig struct {
+	IpsecEnable  bool   `json:"ipsecEnable"`
+	PreSharedKey string `json:"preSharedKey,omitempty"`
+}
+
+type PptpConfig struct {
+	MppeEnable bool `json:"mppeEnable"`
+}
+
+type VpnUser struct {
+	Id        string   `json:"id,omitempty"`
+	Name      string   `json:"name"`
+	Password  string   `json:"password,omitempty"`
+	Status    bool     `json:"status"`
+	ServerIds []string `json:"serverIds"`
+}
+
+type VpnTunnelStatus struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Up         bool   `json:"status"`
+	LocalIp    string `json:"localIp"`
+	PeerIp     string `json:"remoteIp"`
+	PeerName   string `json:"remoteName"`
+	RxBytes    int64  `json:"downBytes"`
+	TxBytes    int64  `json:"upBytes"`
+	Uptime     string `json:"uptime"`
+	UptimeLong int64  `json:"uptimeLong"`
+}
+
+func (v SiteToSiteVpn) Validate() error {
+
+	if v.Name == "" {
+		return fmt.Errorf("site to site vpn name is required")
+	}
+
+	if v.RemoteGateway == "" {
+		return fmt.Errorf("site to site vpn %s: remote gateway is required", v.Name)
+	}
+
+	if len(v.RemoteSubnets) == 0 {
+		return fmt.Errorf("site to site vpn %s: at least one remote subnet is required", v.Name)
+	}
+	for _, subnet := range v.RemoteSubnets {
+		if _, err := netip.ParsePrefix(subnet); err != nil {
+			return fmt.Errorf("site to site vpn %s: invalid remote subnet: %s", v.Name, subnet)
+		}
+	}
+
+	if v.PreSharedKey == "" {
+		return fmt.Errorf("site to site vpn %s: pre-shared key is required", v.Name)
+	}
+
+	if v.IkeVersion != IkeVersion1 && v.IkeVersion != IkeVersion2 {
+		return fmt.Errorf("site to site vpn %s: unknown ike version: %d", v.Name, v.IkeVersion)
+	}
+
+	return nil
+
+}
+
+func (s VpnServer) Validate() error {
+
+	if s.Name == "" {
+		return fmt.Errorf("vpn server name is required")
+	}
+
+	if _, err := netip.ParsePrefix(s.ClientIpPool); err != nil {
+		return fmt.Errorf("vpn server %s: invalid client ip pool: %s", s.Name, s.ClientIpPool)
+	}
+
+	switch s.Type {
+	case VpnServerOpenVpn:
+		if s.OpenVpn == nil {
+			return fmt.Errorf("vpn server %s: openvpn settings are required", s.Name)
+		}
+		if s.OpenVpn.Protocol != "udp" && s.OpenVpn.Protocol != "tcp" {
+			return fmt.Errorf("vpn server %s: openvpn protocol must be udp or tcp", s.Name)
+		}
+		if s.OpenVpn.Port < 1 || s.OpenVpn.Port > 65535 {
+			return fmt.Errorf("vpn server %s: invalid openvpn port: %d", s.Name, s.OpenVpn.Port)
+		}
+	case VpnServerL2tp:
+		if s.L2tp != nil && s.L2tp.IpsecEnable && s.L2tp.PreSharedKey == "" {
+			return fmt.Errorf("vpn server %s: l2tp over ipsec needs a pre-shared key", s.Name)
+		}
+	case VpnServerPptp:
+	default:
+		return fmt.Errorf("vpn server %s: unknown type: %d", s.Name, s.Type)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetSiteToSiteVpns() ([]SiteToSiteVpn, error) {
+
+	var vpns []SiteToSiteVpn
+	if err := c.getPaged(c.siteId, "setting/vpn/siteToSiteVpns", &vpns); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(vpns, func(i, j int) bool {
+		return vpns[i].Name < vpns[j].Name
+	})
+
+	return vpns, nil
+
+}
+
+func (c *Controller) CreateSiteToSiteVpn(vpn SiteToSiteVpn) (SiteToSiteVpn, error) {
+
+	vpn.Id = ""
+	if err := vpn.Validate(); err != nil {
+		return SiteToSiteVpn{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/siteToSiteVpns")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, vpn, &created); err != nil {
+		return SiteToSiteVpn{}, err
+	}
+
+	vpn.Id = created.Id
+	return vpn, nil
+
+}
+
+func (c *Controller) UpdateSiteToSiteVpn(vpn SiteToSiteVpn) error {
+
+	if vpn.Id == "" {
+		return fmt.Errorf("site to site vpn id is required")
+	}
+
+	if err := vpn.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/siteToSiteVpns/"+vpn.Id)
+	return c.doRequest("PATCH", url, vpn, nil)
+
+}
+
+func (c *Controller) DeleteSiteToSiteVpn(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/vpn/siteToSiteVpns/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) GetVpnServers() ([]VpnServer, error) {
+
+	var servers []VpnServer
+	if err := c.getPaged(c.siteId, "setting/vpn/clientToSiteVpnServers", &servers); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	return servers, nil
+
+}
+
+func (c *Controller) CreateVpnServer(server VpnServer) (VpnServer, error) {
+
+	server.Id = ""
+	if err := server.Validate(); err != nil {
+		return VpnServer{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/clientToSiteVpnServers")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, server, &created); err != nil {
+		return VpnServer{}, err
+	}
+
+	server.Id = created.Id
+	return server, nil
+
+}
+
+func (c *Controller) UpdateVpnServer(server VpnServer) error {
+
+	if server.Id == "" {
+		return fmt.Errorf("vpn server id is required")
+	}
+
+	if err := server.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/clientToSiteVpnServers/"+server.Id)
+	return c.doRequest("PATCH", url, server, nil)
+
+}
+
+func (c *Controller) DeleteVpnServer(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/vpn/clientToSiteVpnServers/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) GetVpnUsers() ([]VpnUser, error) {
+
+	var users []VpnUser
+	if err := c.getPaged(c.siteId, "setting/vpn/users", &users); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Name < users[j].Name
+	})
+
+	return users, nil
+
+}
+
+func (c *Controller) CreateVpnUser(user VpnUser) (VpnUser, error) {
+
+	user.Id = ""
+	if user.Name == "" || user.Password == "" {
+		return VpnUser{}, fmt.Errorf("vpn user name and password are required")
+	}
+	if len(user.ServerIds) == 0 {
+		return VpnUser{}, fmt.Errorf("vpn user %s: at least one vpn server is required", user.Name)
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/users")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, user, &created); err != nil {
+		return VpnUser{}, err
+	}
+
+	user.Id = created.Id
+	return user, nil
+
+}
+
+func (c *Controller) UpdateVpnUser(user VpnUser) error {
+
+	if user.Id == "" {
+		return fmt.Errorf("vpn user id is required")
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/users/"+user.Id)
+	return c.doRequest("PATCH", url, user, nil)
+
+}
+
+func (c *Controller) DeleteVpnUser(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/vpn/users/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) GetVpnStatus() ([]VpnTunnelStatus, error) {
+
+	var tunnels []VpnTunnelStatus
+	if err := c.getPaged(c.siteId, "insight/vpn/tunnels", &tunnels); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].Name < tunnels[j].Name
+	})
+
+	return tunnels, nil
+
+}
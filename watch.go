@@ -0,0 +1,331 @@
+package omada
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type WatchEventType string
+
+const (
+	ClientJoined          WatchEventType = "ClientJoined"
+	ClientLeft            WatchEventType = "ClientLeft"
+	ClientIPChanged       WatchEventType = "ClientIPChanged"
+	ClientRoamed          WatchEventType = "ClientRoamed"
+	DeviceOnline          WatchEventType = "DeviceOnline"
+	DeviceOffline         WatchEventType = "DeviceOffline"
+	DeviceFirmwareChanged WatchEventType = "DeviceFirmwareChanged"
+)
+
+const (
+	deviceStatusConnected = 1
+)
+
+// WatchEvent describes a single change between two polls. Client or Device is
+// set depending on the event type, and PreviousClient or PreviousDevice hold
+// the old value for events that change an existing client or device.
+type WatchEvent struct {
+	Type           WatchEventType
+	SiteId         string
+	Time           time.Time
+	Client         *Client
+	PreviousClient *Client
+	Device         *Device
+	PreviousDevice *Device
+}
+
+// WatchSnapshot is the last state seen for a site. Snapshots can be saved
+// and handed back through WatchOptions.Snapshots so a restarted watcher
+// reports what changed while it was down instead of starting from scratch.
+type WatchSnapshot struct {
+	Taken   time.Time
+	Clients map[string]Client
+	Devices map[string]Device
+}
+
+type WatchOptions struct {
+	SiteIds    []string
+	Interval   time.Duration
+	MaxBackoff time.Duration
+	Buffer     int
+	Snapshots  map[string]WatchSnapshot
+
+	// Sites are polled concurrently, but OnEvent and OnError are never called
+	// concurrently with themselves or each other.
+	OnEvent func(WatchEvent)
+	OnError func(siteId string, err error)
+}
+
+type Watcher struct {
+	c         *Controller
+	opts      WatchOptions
+	events    chan WatchEvent
+	mu        sync.Mutex
+	snapshots map[string]WatchSnapshot
+	started   bool
+
+	// callbackMu serializes the OnEvent and OnError callbacks
+	callbackMu sync.Mutex
+}
+
+func (c *Controller) NewWatcher(opts WatchOptions) *Watcher {
+
+	if len(opts.SiteIds) == 0 {
+		opts.SiteIds = []string{c.siteId}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.MaxBackoff < opts.Interval {
+		opts.MaxBackoff = 10 * opts.Interval
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+
+	snapshots := make(map[string]WatchSnapshot)
+	for siteId, snapshot := range opts.Snapshots {
+		snapshots[siteId] = snapshot
+	}
+
+	return &Watcher{
+		c:         c,
+		opts:      opts,
+		events:    make(chan WatchEvent, opts.Buffer),
+		snapshots: snapshots,
+	}
+}
+
+// Events returns the channel events are sent on when no OnEvent callback is
+// set. It is closed when Run returns.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+func (w *Watcher) Snapshots() map[string]WatchSnapshot {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshots := make(map[string]WatchSnapshot)
+	for siteId, snapshot := range w.snapshots {
+		snapshots[siteId] = snapshot
+	}
+
+	return snapshots
+
+}
+
+// Run polls every site until ctx is cancelled. A watcher can only be run
+// once, as the events channel is closed when Run returns.
+func (w *Watcher) Run(ctx context.Context) error {
+
+	w.mu.Lock()
+	started := w.started
+	w.started = true
+	w.mu.Unlock()
+	if started {
+		return fmt.Errorf("watcher has already been run")
+	}
+
+	defer close(w.events)
+
+	var wg sync.WaitGroup
+	for _, siteId := range w.opts.SiteIds {
+		wg.Add(1)
+		go func(siteId string) {
+			defer wg.Done()
+			w.watchSite(ctx, siteId)
+		}(siteId)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+
+}
+
+func (w *Watcher) watchSite(ctx context.Context, siteId string) {
+
+	delay := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		// select picks at random when both are ready
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := w.poll(ctx, siteId); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if w.opts.OnError != nil {
+				w.callbackMu.Lock()
+				w.opts.OnError(siteId, err)
+				w.callbackMu.Unlock()
+			}
+			delay = nextBackoff(delay, w.opts.Interval, w.opts.MaxBackoff)
+			continue
+		}
+
+		delay = w.opts.Interval
+	}
+
+}
+
+func nextBackoff(delay time.Duration, interval time.Duration, max time.Duration) time.Duration {
+
+	if delay < interval {
+		return interval
+	}
+
+	delay *= 2
+	if delay > max {
+		return max
+	}
+
+	return delay
+
+}
+
+func (w *Watcher) poll(ctx context.Context, siteId string) error {
+
+	c := w.c.WithContext(ctx)
+
+	clients, devices, err := c.getSiteHosts(siteId)
+	if err != nil {
+		return err
+	}
+
+	current := WatchSnapshot{
+		Taken:   time.Now(),
+		Clients: make(map[string]Client),
+		Devices: make(map[string]Device),
+	}
+	for _, client := range clients {
+		current.Clients[client.MAC] = client
+	}
+	for _, device := range devices {
+		current.Devices[device.Mac] = device
+	}
+
+	w.mu.Lock()
+	previous, ok := w.snapshots[siteId]
+	w.snapshots[siteId] = current
+	w.mu.Unlock()
+
+	// the first poll without a saved snapshot only sets the baseline
+	if !ok {
+		return nil
+	}
+
+	for _, event := range diffSnapshots(siteId, previous, current) {
+		if !w.emit(ctx, event) {
+			return nil
+		}
+	}
+
+	return nil
+
+}
+
+func (w *Watcher) emit(ctx context.Context, event WatchEvent) bool {
+
+	if w.opts.OnEvent != nil {
+		w.callbackMu.Lock()
+		defer w.callbackMu.Unlock()
+		w.opts.OnEvent(event)
+		return true
+	}
+
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+
+}
+
+func diffSnapshots(siteId string, previous WatchSnapshot, current WatchSnapshot) []WatchEvent {
+
+	var events []WatchEvent
+	newEvent := func(eventType WatchEventType) WatchEvent {
+		return WatchEvent{Type: eventType, SiteId: siteId, Time: current.Taken}
+	}
+
+	for mac, client := range current.Clients {
+		client := client
+		old, ok := previous.Clients[mac]
+		if !ok {
+			event := newEvent(ClientJoined)
+			event.Client = &client
+			events = append(events, event)
+			continue
+		}
+
+		if old.Ip != client.Ip {
+			event := newEvent(ClientIPChanged)
+			event.Client, event.PreviousClient = &client, &old
+			events = append(events, event)
+		}
+		if old.ApMac != "" && client.ApMac != "" && old.ApMac != client.ApMac {
+			event := newEvent(ClientRoamed)
+			event.Client, event.PreviousClient = &client, &old
+			events = append(events, event)
+		}
+	}
+
+	for mac, old := range previous.Clients {
+		old := old
+		if _, ok := current.Clients[mac]; !ok {
+			event := newEvent(ClientLeft)
+			event.Client = &old
+			events = append(events, event)
+		}
+	}
+
+	for mac, device := range current.Devices {
+		device := device
+		old, ok := previous.Devices[mac]
+		wasOnline := ok && old.StatusCategory == deviceStatusConnected
+		isOnline := device.StatusCategory == deviceStatusConnected
+
+		if isOnline && !wasOnline {
+			event := newEvent(DeviceOnline)
+			event.Device = &device
+			if ok {
+				event.PreviousDevice = &old
+			}
+			events = append(events, event)
+		}
+		if !isOnline && wasOnline {
+			event := newEvent(DeviceOffline)
+			event.Device, event.PreviousDevice = &device, &old
+			events = append(events, event)
+		}
+		if ok && old.FirmwareVersion != "" && old.FirmwareVersion != device.FirmwareVersion {
+			event := newEvent(DeviceFirmwareChanged)
+			event.Device, event.PreviousDevice = &device, &old
+			events = append(events, event)
+		}
+	}
+
+	for mac, old := range previous.Devices {
+		old := old
+		if _, ok := current.Devices[mac]; !ok && old.StatusCategory == deviceStatusConnected {
+			event := newEvent(DeviceOffline)
+			event.Device = &old
+			events = append(events, event)
+		}
+	}
+
+	return events
+
+}
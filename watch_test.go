@@ -0,0 +1,206 @@
+package omada
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+
+	taken := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	online := Device{Mac: "AA-00-00-00-00-01", StatusCategory: deviceStatusConnected, FirmwareVersion: "1.0"}
+	offline := online
+	offline.StatusCategory = 0
+	upgraded := online
+	upgraded.FirmwareVersion = "1.1"
+
+	laptop := Client{MAC: "BB-00-00-00-00-01", Ip: "10.0.0.5", ApMac: "AA-00-00-00-00-01"}
+	moved := laptop
+	moved.Ip = "10.0.0.6"
+	roamed := laptop
+	roamed.ApMac = "AA-00-00-00-00-02"
+	wired := laptop
+	wired.ApMac = ""
+
+	tests := []struct {
+		name     string
+		previous WatchSnapshot
+		current  WatchSnapshot
+		want     []WatchEventType
+	}{
+		{
+			name: "no changes",
+			previous: WatchSnapshot{
+				Clients: map[string]Client{laptop.MAC: laptop},
+				Devices: map[string]Device{online.Mac: online},
+			},
+			current: WatchSnapshot{
+				Clients: map[string]Client{laptop.MAC: laptop},
+				Devices: map[string]Device{online.Mac: online},
+			},
+		},
+		{
+			name:     "client joined",
+			previous: WatchSnapshot{},
+			current:  WatchSnapshot{Clients: map[string]Client{laptop.MAC: laptop}},
+			want:     []WatchEventType{ClientJoined},
+		},
+		{
+			name:     "client left",
+			previous: WatchSnapshot{Clients: map[string]Client{laptop.MAC: laptop}},
+			current:  WatchSnapshot{},
+			want:     []WatchEventType{ClientLeft},
+		},
+		{
+			name:     "client ip changed",
+			previous: WatchSnapshot{Clients: map[string]Client{laptop.MAC: laptop}},
+			current:  WatchSnapshot{Clients: map[string]Client{laptop.MAC: moved}},
+			want:     []WatchEventType{ClientIPChanged},
+		},
+		{
+			name:     "client roamed",
+			previous: WatchSnapshot{Clients: map[string]Client{laptop.MAC: laptop}},
+			current:  WatchSnapshot{Clients: map[string]Client{laptop.MAC: roamed}},
+			want:     []WatchEventType{ClientRoamed},
+		},
+		{
+			name:     "client moved to a wired port is not a roam",
+			previous: WatchSnapshot{Clients: map[string]Client{laptop.MAC: laptop}},
+			current:  WatchSnapshot{Clients: map[string]Client{laptop.MAC: wired}},
+		},
+		{
+			name:     "device came online",
+			previous: WatchSnapshot{Devices: map[string]Device{online.Mac: offline}},
+			current:  WatchSnapshot{Devices: map[string]Device{online.Mac: online}},
+			want:     []WatchEventType{DeviceOnline},
+		},
+		{
+			name:     "new device online",
+			previous: WatchSnapshot{},
+			current:  WatchSnapshot{Devices: map[string]Device{online.Mac: online}},
+			want:     []WatchEventType{DeviceOnline},
+		},
+		{
+			name:     "device went offline",
+			previous: WatchSnapshot{Devices: map[string]Device{online.Mac: online}},
+			current:  WatchSnapshot{Devices: map[string]Device{online.Mac: offline}},
+			want:     []WatchEventType{DeviceOffline},
+		},
+		{
+			name:     "online device removed",
+			previous: WatchSnapshot{Devices: map[string]Device{online.Mac: online}},
+			current:  WatchSnapshot{},
+			want:     []WatchEventType{DeviceOffline},
+		},
+		{
+			name:     "offline device removed",
+			previous: WatchSnapshot{Devices: map[string]Device{online.Mac: offline}},
+			current:  WatchSnapshot{},
+		},
+		{
+			name:     "firmware changed",
+			previous: WatchSnapshot{Devices: map[string]Device{online.Mac: online}},
+			current:  WatchSnapshot{Devices: map[string]Device{online.Mac: upgraded}},
+			want:     []WatchEventType{DeviceFirmwareChanged},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.current.Taken = taken
+			events := diffSnapshots("site", test.previous, test.current)
+
+			var got []WatchEventType
+			for _, event := range events {
+				if event.SiteId != "site" || !event.Time.Equal(taken) {
+					t.Errorf("event %s: got site %q time %s", event.Type, event.SiteId, event.Time)
+				}
+				got = append(got, event.Type)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+			if len(got) != len(test.want) {
+				t.Fatalf("got events %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got events %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+
+}
+
+func TestDiffSnapshotsPreviousValues(t *testing.T) {
+
+	old := Client{MAC: "BB-00-00-00-00-01", Ip: "10.0.0.5"}
+	updated := old
+	updated.Ip = "10.0.0.6"
+
+	events := diffSnapshots("site",
+		WatchSnapshot{Clients: map[string]Client{old.MAC: old}},
+		WatchSnapshot{Clients: map[string]Client{old.MAC: updated}},
+	)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].PreviousClient.Ip != "10.0.0.5" || events[0].Client.Ip != "10.0.0.6" {
+		t.Errorf("got previous %s current %s", events[0].PreviousClient.Ip, events[0].Client.Ip)
+	}
+
+}
+
+func TestWatcherRunTwice(t *testing.T) {
+
+	c := Controller{siteId: "site"}
+	w := c.NewWatcher(WatchOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Run(ctx); err != context.Canceled {
+		t.Fatalf("first run: got %v, want %v", err, context.Canceled)
+	}
+	if err := w.Run(ctx); err == nil {
+		t.Fatal("second run: expected an error")
+	}
+
+}
+
+func TestWatcherCancelsInFlightPoll(t *testing.T) {
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := Controller{httpClient: server.Client(), baseURL: server.URL, siteId: "site"}
+	w := c.NewWatcher(WatchOptions{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the context was cancelled")
+	}
+
+}
@@ -0,0 +1,380 @@
+package omada
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+type WireGuard struct {
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Status     bool   `json:"status"`
+	ListenPort int    `json:"listenPort"`
+	LocalIp    string `json:"localIp"`
+	Mtu        int    `json:"mtu"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	PublicKey  string `json:"publicKey,omitempty"`
+}
+
+func (w WireGuard) Validate() error {
+
+	if w.Name == "" {
+		return fmt.Errorf("wireguard name is required")
+	}
+
+	if w.ListenPort < 1 || w.ListenPort > 65535 {
+		return fmt.Errorf("wireguard %s: invalid listen port: %d", w.Name, w.ListenPort)
+	}
+
+	if _, err := netip.ParsePrefix(w.LocalIp); err != nil {
+		return fmt.Errorf("wireguard %s: local ip must be an address with prefix length: %s", w.Name, w.LocalIp)
+	}
+
+	if w.Mtu != 0 && (w.Mtu < 1280 || w.Mtu > 1420) {
+		return fmt.Errorf("wireguard %s: mtu must be between 1280 and 1420, got: %d", w.Name, w.Mtu)
+	}
+
+	return nil
+
+}
+
+func (c *Controller) GetWireGuards() ([]WireGuard, error) {
+
+	var interfaces []WireGuard
+	if err := c.getPaged(c.siteId, "setting/vpn/wireguards", &interfaces); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].Name < interfaces[j].Name
+	})
+
+	return interfaces, nil
+
+}
+
+func (c *Controller) GetWireGuard(id string) (WireGuard, error) {
+
+	interfaces, err := c.GetWireGuards()
+	if err != nil {
+		return WireGuard{}, err
+	}
+
+	for _, wg := range interfaces {
+		if wg.Id == id {
+			return wg, nil
+		}
+	}
+
+	return WireGuard{}, fmt.Errorf("wireguard not found: %s", id)
+
+}
+
+func (c *Controller) CreateWireGuard(wg WireGuard) (WireGuard, error) {
+
+	wg.Id = ""
+	if err := wg.Validate(); err != nil {
+		return WireGuard{}, err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/wireguards")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, wg, &created); err != nil {
+		return WireGuard{}, err
+	}
+
+	wg.Id = created.Id
+	return wg, nil
+
+}
+
+func (c *Controller) UpdateWireGuard(wg WireGuard) error {
+
+	if wg.Id == "" {
+		return fmt.Errorf("wireguard id is required")
+	}
+
+	if err := wg.Validate(); err != nil {
+		return err
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/wireguards/"+wg.Id)
+	return c.doRequest("PATCH", url, wg, nil)
+
+}
+
+func (c *Controller) DeleteWireGuard(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/vpn/wireguards/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+type WireGuardPeer struct {
+	Id           string   `json:"id,omitempty"`
+	Name         string   `json:"name"`
+	Status       bool     `json:"status"`
+	InterfaceId  string   `json:"interfaceId"`
+	PublicKey    string   `json:"publicKey"`
+	AllowAddress []string `json:"allowAddress"`
+	PresharedKey string   `json:"presharedKey,omitempty"`
+	KeepAlive    int      `json:"keepAlive,omitempty"`
+	Comment      string   `json:"comment,omitempty"`
+}
+
+type WireGuardPeerOptions struct {
+	InterfaceId  string
+	Name         string
+	Endpoint     string
+	Dns          []string
+	KeepAlive    int
+	PresharedKey bool
+}
+
+type WireGuardClientConfig struct {
+	Peer       WireGuardPeer
+	PrivateKey string
+	Address    netip.Prefix
+	AllowedIps []netip.Prefix
+	Config     string
+}
+
+func (c *Controller) GetWireGuardPeers(interfaceId string) ([]WireGuardPeer, error) {
+
+	var all []WireGuardPeer
+	if err := c.getPaged(c.siteId, "setting/vpn/wireguard/peers", &all); err != nil {
+		return nil, err
+	}
+
+	var peers []WireGuardPeer
+	for _, peer := range all {
+		if interfaceId == "" || peer.InterfaceId == interfaceId {
+			peers = append(peers, peer)
+		}
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Name < peers[j].Name
+	})
+
+	return peers, nil
+
+}
+
+func (c *Controller) RevokeWireGuardPeer(id string) error {
+
+	url := c.siteURL(c.siteId, "setting/vpn/wireguard/peers/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+// ProvisionWireGuardPeer creates a peer for a remote user on a wireguard
+// interface. The keypair is generated locally and the private key is only
+// ever returned in the rendered wg-quick config, never sent to the controller.
+func (c *Controller) ProvisionWireGuardPeer(opts WireGuardPeerOptions) (WireGuardClientConfig, error) {
+
+	if opts.Name == "" {
+		return WireGuardClientConfig{}, fmt.Errorf("wireguard peer name is required")
+	}
+	if opts.Endpoint == "" {
+		return WireGuardClientConfig{}, fmt.Errorf("wireguard peer %s: endpoint is required", opts.Name)
+	}
+
+	wg, err := c.GetWireGuard(opts.InterfaceId)
+	if err != nil {
+		return WireGuardClientConfig{}, err
+	}
+	if wg.PublicKey == "" {
+		return WireGuardClientConfig{}, fmt.Errorf("wireguard %s has no public key", wg.Name)
+	}
+
+	peers, err := c.GetWireGuardPeers(wg.Id)
+	if err != nil {
+		return WireGuardClientConfig{}, err
+	}
+
+	address, err := nextFreeTunnelAddress(wg, peers)
+	if err != nil {
+		return WireGuardClientConfig{}, err
+	}
+
+	allowedIps, err := c.wireGuardAllowedIps(wg)
+	if err != nil {
+		return WireGuardClientConfig{}, err
+	}
+
+	privateKey, publicKey, err := GenerateWireGuardKeyPair()
+	if err != nil {
+		return WireGuardClientConfig{}, err
+	}
+
+	peer := WireGuardPeer{
+		Name:         opts.Name,
+		Status:       true,
+		InterfaceId:  wg.Id,
+		PublicKey:    publicKey,
+		AllowAddress: []string{address.String()},
+		KeepAlive:    opts.KeepAlive,
+	}
+	if opts.PresharedKey {
+		peer.PresharedKey, err = GenerateWireGuardPresharedKey()
+		if err != nil {
+			return WireGuardClientConfig{}, err
+		}
+	}
+
+	url := c.siteURL(c.siteId, "setting/vpn/wireguard/peers")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, peer, &created); err != nil {
+		return WireGuardClientConfig{}, err
+	}
+	peer.Id = created.Id
+
+	config := WireGuardClientConfig{
+		Peer:       peer,
+		PrivateKey: privateKey,
+		Address:    address,
+		AllowedIps: allowedIps,
+	}
+	config.Config = renderWgQuick(config, wg, opts)
+
+	return config, nil
+
+}
+
+func GenerateWireGuardKeyPair() (string, string, error) {
+
+	key := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", "", err
+	}
+
+	// clamp the scalar the same way wg genkey does
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+
+	public, err := curve25519.X25519(key, curve25519.Basepoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKey := base64.StdEncoding.EncodeToString(key)
+	publicKey := base64.StdEncoding.EncodeToString(public)
+
+	return privateKey, publicKey, nil
+
+}
+
+func GenerateWireGuardPresharedKey() (string, error) {
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+
+}
+
+// nextFreeTunnelAddress picks the lowest host address in the interface's
+// tunnel subnet that is not the interface itself or already given to a peer.
+func nextFreeTunnelAddress(wg WireGuard, peers []WireGuardPeer) (netip.Prefix, error) {
+
+	local, err := netip.ParsePrefix(wg.LocalIp)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("wireguard %s: invalid local ip: %s", wg.Name, wg.LocalIp)
+	}
+	subnet := local.Masked()
+
+	used := map[netip.Addr]bool{local.Addr(): true}
+	for _, peer := range peers {
+		for _, allowed := range peer.AllowAddress {
+			prefix, err := netip.ParsePrefix(allowed)
+			if err != nil {
+				continue
+			}
+			if subnet.Contains(prefix.Addr()) {
+				used[prefix.Addr()] = true
+			}
+		}
+	}
+
+	for addr := subnet.Addr().Next(); subnet.Contains(addr); addr = addr.Next() {
+		if addr.Is4() && !subnet.Contains(addr.Next()) {
+			// broadcast address
+			break
+		}
+		if !used[addr] {
+			return netip.PrefixFrom(addr, addr.BitLen()), nil
+		}
+	}
+
+	return netip.Prefix{}, fmt.Errorf("wireguard %s: no free tunnel addresses left in %s", wg.Name, subnet)
+
+}
+
+func (c *Controller) wireGuardAllowedIps(wg WireGuard) ([]netip.Prefix, error) {
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []netip.Prefix
+	if local, err := netip.ParsePrefix(wg.LocalIp); err == nil {
+		allowed = append(allowed, local.Masked())
+	}
+
+	for _, network := range networks {
+		prefix, err := network.Prefix()
+		if err != nil {
+			continue
+		}
+		allowed = append(allowed, prefix)
+	}
+
+	return allowed, nil
+
+}
+
+func renderWgQuick(config WireGuardClientConfig, wg WireGuard, opts WireGuardPeerOptions) string {
+
+	var sb strings.Builder
+
+	sb.WriteString("[Interface]\n")
+	fmt.Fprintf(&sb, "PrivateKey = %s\n", config.PrivateKey)
+	fmt.Fprintf(&sb, "Address = %s\n", config.Address)
+	if len(opts.Dns) > 0 {
+		fmt.Fprintf(&sb, "DNS = %s\n", strings.Join(opts.Dns, ", "))
+	}
+	if wg.Mtu != 0 {
+		fmt.Fprintf(&sb, "MTU = %d\n", wg.Mtu)
+	}
+
+	var allowed []string
+	for _, prefix := range config.AllowedIps {
+		allowed = append(allowed, prefix.String())
+	}
+
+	sb.WriteString("\n[Peer]\n")
+	fmt.Fprintf(&sb, "PublicKey = %s\n", wg.PublicKey)
+	if config.Peer.PresharedKey != "" {
+		fmt.Fprintf(&sb, "PresharedKey = %s\n", config.Peer.PresharedKey)
+	}
+	fmt.Fprintf(&sb, "AllowedIPs = %s\n", strings.Join(allowed, ", "))
+	fmt.Fprintf(&sb, "Endpoint = %s\n", opts.Endpoint)
+	if opts.KeepAlive > 0 {
+		fmt.Fprintf(&sb, "PersistentKeepalive = %d\n", opts.KeepAlive)
+	}
+
+	return sb.String()
+
+}
@@ -0,0 +1,409 @@
+package omada
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	SsidSecurityNone          = 0
+	SsidSecurityWpaEnterprise = 2
+	SsidSecurityWpaPersonal   = 3
+	SsidSecurityPpsk          = 4
+)
+
+const (
+	SsidBand2G = 1
+	SsidBand5G = 2
+	SsidBand6G = 4
+)
+
+const (
+	ssidVlanModeDefault = 0
+	ssidVlanModeNetwork = 1
+)
+
+const (
+	MacFilterAllow = 0
+	MacFilterDeny  = 1
+)
+
+type WlanGroup struct {
+	Id      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Primary bool   `json:"primary,omitempty"`
+	Site    string `json:"site,omitempty"`
+}
+
+type Ssid struct {
+	Id                 string           `json:"id,omitempty"`
+	WlanId             string           `json:"wlanId,omitempty"`
+	Name               string           `json:"name"`
+	Band               int              `json:"band"`
+	GuestNetEnable     bool             `json:"guestNetEnable"`
+	Security           int              `json:"security"`
+	Broadcast          bool             `json:"broadcast"`
+	VlanEnable         bool             `json:"vlanEnable"`
+	VlanSetting        *SsidVlanSetting `json:"vlanSetting,omitempty"`
+	PskSetting         *SsidPskSetting  `json:"pskSetting,omitempty"`
+	PpskSetting        *SsidPpskSetting `json:"ppskSetting,omitempty"`
+	BandSteerEnable    bool             `json:"bandSteerEnable"`
+	RateLimit          SsidRateLimit    `json:"rateLimit"`
+	WlanScheduleEnable bool             `json:"wlanScheduleEnable"`
+	ScheduleId         string           `json:"scheduleId,omitempty"`
+	MacFilterEnable    bool             `json:"macFilterEnable"`
+	MacFilterPolicy    int              `json:"policy"`
+	MacFilterId        string           `json:"macFilterId,omitempty"`
+}
+
+type SsidVlanSetting struct {
+	Mode         int                   `json:"mode"`
+	CustomConfig SsidVlanCustomSetting `json:"customConfig"`
+}
+
+type SsidVlanCustomSetting struct {
+	LanNetworkId string `json:"lanNetworkId,omitempty"`
+	VlanId       int    `json:"vlanId,omitempty"`
+}
+
+type SsidPskSetting struct {
+	SecurityKey       string `json:"securityKey"`
+	VersionPsk        int    `json:"versionPsk"`
+	EncryptionPsk     int    `json:"encryptionPsk"`
+	GikRekeyPskEnable bool   `json:"gikRekeyPskEnable"`
+}
+
+type SsidPpskSetting struct {
+	PpskProfileId string `json:"ppskProfileId"`
+}
+
+type SsidRateLimit struct {
+	DownLimitEnable bool `json:"downLimitEnable"`
+	DownLimit       int  `json:"downLimit"`
+	DownLimitType   int  `json:"downLimitType"`
+	UpLimitEnable   bool `json:"upLimitEnable"`
+	UpLimit         int  `json:"upLimit"`
+	UpLimitType     int  `json:"upLimitType"`
+}
+
+func (s Ssid) Hidden() bool {
+	return !s.Broadcast
+}
+
+func (s *Ssid) SetHidden(hidden bool) {
+	s.Broadcast = !hidden
+}
+
+func (s *Ssid) BindNetwork(network OmadaNetwork) {
+	s.VlanEnable = true
+	s.VlanSetting = &SsidVlanSetting{
+		Mode:         ssidVlanModeNetwork,
+		CustomConfig: SsidVlanCustomSetting{LanNetworkId: network.Id},
+	}
+}
+
+func (s *Ssid) UnbindNetwork() {
+	s.VlanEnable = false
+	s.VlanSetting = &SsidVlanSetting{Mode: ssidVlanModeDefault}
+}
+
+func (s Ssid) Validate() error {
+
+	if s.Name == "" {
+		return fmt.Errorf("ssid name is required")
+	}
+	if len(s.Name) > 32 {
+		return fmt.Errorf("ssid %s: name must be at most 32 bytes", s.Name)
+	}
+
+	switch s.Security {
+	case SsidSecurityNone, SsidSecurityWpaEnterprise:
+	case SsidSecurityWpaPersonal:
+		if s.PskSetting == nil {
+			return fmt.Errorf("ssid %s: wpa personal requires a psk setting", s.Name)
+		}
+		if err := validatePassphrase(s.PskSetting.SecurityKey); err != nil {
+			return fmt.Errorf("ssid %s: %w", s.Name, err)
+		}
+	case SsidSecurityPpsk:
+		if s.PpskSetting == nil || s.PpskSetting.PpskProfileId == "" {
+			return fmt.Errorf("ssid %s: ppsk requires a ppsk profile", s.Name)
+		}
+	default:
+		return fmt.Errorf("ssid %s: unknown security mode: %d", s.Name, s.Security)
+	}
+
+	if s.Band == 0 || s.Band&^(SsidBand2G|SsidBand5G|SsidBand6G) != 0 {
+		return fmt.Errorf("ssid %s: invalid band: %d", s.Name, s.Band)
+	}
+
+	return nil
+
+}
+
+func validatePassphrase(passphrase string) error {
+
+	if len(passphrase) == 64 {
+		for _, r := range passphrase {
+			if !isHex(r) {
+				return fmt.Errorf("64 character passphrase must be hex")
+			}
+		}
+		return nil
+	}
+
+	if len(passphrase) < 8 || len(passphrase) > 63 {
+		return fmt.Errorf("passphrase must be between 8 and 63 characters, got: %d", len(passphrase))
+	}
+
+	for _, r := range passphrase {
+		if r < 0x20 || r > 0x7e {
+			return fmt.Errorf("passphrase must be printable ascii")
+		}
+	}
+
+	return nil
+
+}
+
+func isHex(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func (c *Controller) GetWlanGroups() ([]WlanGroup, error) {
+	return c.getWlanGroups(c.siteId)
+}
+
+func (c *Controller) getWlanGroups(siteId string) ([]WlanGroup, error) {
+
+	var groups []WlanGroup
+	if err := c.getPaged(siteId, "setting/wlans", &groups); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups, nil
+
+}
+
+func (c *Controller) GetWlanGroup(id string) (WlanGroup, error) {
+
+	groups, err := c.GetWlanGroups()
+	if err != nil {
+		return WlanGroup{}, err
+	}
+
+	for _, group := range groups {
+		if group.Id == id {
+			return group, nil
+		}
+	}
+
+	return WlanGroup{}, fmt.Errorf("wlan group not found: %s", id)
+
+}
+
+func (c *Controller) CreateWlanGroup(name string) (WlanGroup, error) {
+
+	group := WlanGroup{Name: name}
+	url := c.siteURL(c.siteId, "setting/wlans")
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, group, &created); err != nil {
+		return WlanGroup{}, err
+	}
+
+	group.Id = created.Id
+	return group, nil
+
+}
+
+func (c *Controller) UpdateWlanGroup(group WlanGroup) error {
+
+	if group.Id == "" {
+		return fmt.Errorf("wlan group id is required")
+	}
+
+	url := c.siteURL(c.siteId, "setting/wlans/"+group.Id)
+	return c.doRequest("PATCH", url, group, nil)
+
+}
+
+func (c *Controller) DeleteWlanGroup(id string) error {
+
+	group, err := c.GetWlanGroup(id)
+	if err != nil {
+		return err
+	}
+	if group.Primary {
+		return fmt.Errorf("the default wlan group cannot be deleted: %s", group.Name)
+	}
+
+	url := c.siteURL(c.siteId, "setting/wlans/"+id)
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) GetSsids(wlanId string) ([]Ssid, error) {
+	return c.getSsids(c.siteId, wlanId)
+}
+
+func (c *Controller) getSsids(siteId string, wlanId string) ([]Ssid, error) {
+
+	var ssids []Ssid
+	if err := c.getPaged(siteId, fmt.Sprintf("setting/wlans/%s/ssids", wlanId), &ssids); err != nil {
+		return nil, err
+	}
+
+	for i := range ssids {
+		ssids[i].WlanId = wlanId
+	}
+
+	sort.Slice(ssids, func(i, j int) bool {
+		return ssids[i].Name < ssids[j].Name
+	})
+
+	return ssids, nil
+
+}
+
+func (c *Controller) GetSsid(wlanId string, id string) (Ssid, error) {
+
+	ssids, err := c.GetSsids(wlanId)
+	if err != nil {
+		return Ssid{}, err
+	}
+
+	for _, ssid := range ssids {
+		if ssid.Id == id {
+			return ssid, nil
+		}
+	}
+
+	return Ssid{}, fmt.Errorf("ssid not found: %s", id)
+
+}
+
+func (c *Controller) CreateSsid(wlanId string, ssid Ssid) (Ssid, error) {
+
+	ssid.Id = ""
+	ssid.WlanId = wlanId
+	if err := c.checkSsid(ssid); err != nil {
+		return Ssid{}, err
+	}
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("setting/wlans/%s/ssids", wlanId))
+
+	var created createdResponse
+	if err := c.doRequest("POST", url, ssid, &created); err != nil {
+		return Ssid{}, err
+	}
+
+	ssid.Id = created.Id
+	return ssid, nil
+
+}
+
+func (c *Controller) UpdateSsid(wlanId string, ssid Ssid) error {
+
+	if ssid.Id == "" {
+		return fmt.Errorf("ssid id is required")
+	}
+
+	ssid.WlanId = wlanId
+	if err := c.checkSsid(ssid); err != nil {
+		return err
+	}
+
+	return c.updateSsid(c.siteId, wlanId, ssid)
+
+}
+
+func (c *Controller) updateSsid(siteId string, wlanId string, ssid Ssid) error {
+
+	url := c.siteURL(siteId, fmt.Sprintf("setting/wlans/%s/ssids/%s", wlanId, ssid.Id))
+	return c.doRequest("PATCH", url, ssid, nil)
+
+}
+
+func (c *Controller) DeleteSsid(wlanId string, id string) error {
+
+	url := c.siteURL(c.siteId, fmt.Sprintf("setting/wlans/%s/ssids/%s", wlanId, id))
+	return c.doRequest("DELETE", url, nil, nil)
+
+}
+
+func (c *Controller) SetSsidPassphrase(wlanId string, id string, passphrase string) error {
+
+	ssid, err := c.GetSsid(wlanId, id)
+	if err != nil {
+		return err
+	}
+
+	if ssid.Security != SsidSecurityWpaPersonal || ssid.PskSetting == nil {
+		return fmt.Errorf("ssid %s is not using wpa personal", ssid.Name)
+	}
+
+	ssid.PskSetting.SecurityKey = passphrase
+	return c.UpdateSsid(wlanId, ssid)
+
+}
+
+func (c *Controller) checkSsid(ssid Ssid) error {
+
+	if err := ssid.Validate(); err != nil {
+		return err
+	}
+
+	if !ssid.VlanEnable || ssid.VlanSetting == nil || ssid.VlanSetting.Mode != ssidVlanModeNetwork {
+		return nil
+	}
+
+	networks, err := c.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	for _, network := range networks {
+		if network.Id == ssid.VlanSetting.CustomConfig.LanNetworkId {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("ssid %s refers to unknown network: %s", ssid.Name, ssid.VlanSetting.CustomConfig.LanNetworkId)
+
+}
+
+// ResolveWlanGroups fills in the wlan group name of each device, looking the
+// group up in the device's own site.
+func (c *Controller) ResolveWlanGroups(devices []Device) ([]Device, error) {
+
+	names := make(map[string]map[string]string)
+	resolved := make([]Device, len(devices))
+	for i, device := range devices {
+		siteId := device.SiteId
+		if siteId == "" {
+			siteId = c.siteId
+		}
+
+		if _, ok := names[siteId]; !ok {
+			groups, err := c.getWlanGroups(siteId)
+			if err != nil {
+				return nil, err
+			}
+			names[siteId] = make(map[string]string)
+			for _, group := range groups {
+				names[siteId][group.Id] = group.Name
+			}
+		}
+
+		device.WlanGroupName = names[siteId][device.WlanGroup]
+		resolved[i] = device
+	}
+
+	return resolved, nil
+
+}
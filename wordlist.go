@@ -0,0 +1,35 @@
+package omada
+
+// passphraseWords holds 256 short, unambiguous words so each word adds
+// 8 bits of entropy to a generated passphrase.
+var passphraseWords = []string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby", "back",
+	"ball", "band", "bank", "base", "bath", "bear", "beat", "bell", "belt",
+	"best", "bird", "blow", "blue", "boat", "body", "bone", "book", "boot",
+	"born", "boss", "both", "bowl", "bulk", "burn", "bush", "busy", "cafe",
+	"cake", "call", "calm", "came", "camp", "card", "care", "cart", "case",
+	"cash", "cast", "cell", "chef", "chip", "city", "clay", "club", "coal",
+	"coat", "code", "cold", "come", "cook", "cool", "cope", "copy", "core",
+	"corn", "cost", "crew", "crop", "dark", "data", "date", "dawn", "days",
+	"deal", "dear", "deep", "deer", "desk", "dial", "diet", "disk", "dock",
+	"door", "dose", "down", "draw", "drop", "drum", "duck", "dust", "duty",
+	"each", "earn", "ease", "east", "easy", "edge", "else", "even", "ever",
+	"face", "fact", "fair", "fall", "farm", "fast", "fear", "feel", "file",
+	"fill", "film", "find", "fine", "fire", "firm", "fish", "five", "flag",
+	"flat", "flow", "folk", "food", "foot", "form", "fort", "four", "free",
+	"frog", "fuel", "full", "fund", "gain", "game", "gate", "gear", "gift",
+	"girl", "give", "glad", "goal", "gold", "golf", "good", "gray", "grew",
+	"grid", "grow", "gulf", "hair", "half", "hall", "hand", "hang", "hard",
+	"harp", "head", "hear", "heat", "held", "help", "herb", "hero", "high",
+	"hill", "hint", "hold", "hole", "home", "hope", "horn", "host", "hour",
+	"huge", "idea", "inch", "into", "iron", "item", "jazz", "join", "joke",
+	"jump", "jury", "just", "keen", "keep", "kick", "kind", "king", "kite",
+	"knee", "knot", "lake", "lamp", "land", "lane", "last", "late", "lawn",
+	"lead", "leaf", "left", "lens", "life", "lift", "lime", "line", "link",
+	"lion", "list", "live", "load", "loan", "lock", "logo", "long", "look",
+	"loop", "lord", "love", "luck", "lung", "made", "mail", "main", "make",
+	"mall", "many", "maps", "mark", "mass", "meal", "meet", "melt", "menu",
+	"mild", "milk", "mill", "mind", "mine", "mint", "miss", "mode", "moon",
+	"more", "most", "move", "much", "nail", "name", "navy", "near", "neat",
+	"neck", "need", "nest", "news",
+}